@@ -2,10 +2,11 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
 	"log"
 	httpImport "net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	jwt "github.com/appleboy/gin-jwt/v2"
@@ -13,34 +14,24 @@ import (
 	_ "github.com/heroku/x/hmetrics/onload"
 	_ "github.com/lib/pq"
 	"github.com/philLITERALLY/wodland-service/internal/data"
-	"github.com/philLITERALLY/wodland-service/internal/data/db"
 	"github.com/philLITERALLY/wodland-service/internal/http"
+	"github.com/philLITERALLY/wodland-service/internal/lockout"
+	"github.com/philLITERALLY/wodland-service/internal/mail"
+	"github.com/philLITERALLY/wodland-service/internal/notify"
+	"github.com/philLITERALLY/wodland-service/internal/ocr"
+	"github.com/philLITERALLY/wodland-service/internal/oidc"
+	"github.com/philLITERALLY/wodland-service/internal/ratelimit"
+	"github.com/philLITERALLY/wodland-service/internal/storage"
 )
 
 var (
 	idKey       = "id"
 	usernameKey = "username"
 	roleKey     = "role"
+	sessionKey  = "sid"
+	tokenIDKey  = "jti"
 )
 
-func helloHandler(c *gin.Context) {
-	claims := jwt.ExtractClaims(c)
-	user, _ := c.Get(usernameKey)
-
-	c.JSON(200, gin.H{
-		"Claims": map[string]interface{}{
-			"id":       claims[idKey],
-			"username": claims[usernameKey],
-			"role":     claims[roleKey],
-		},
-		"Context": map[string]interface{}{
-			"id":       user.(*data.User).ID,
-			"username": user.(*data.User).Username,
-			"role":     user.(*data.User).Role,
-		},
-	})
-}
-
 func main() {
 	port := os.Getenv("PORT")
 
@@ -54,49 +45,172 @@ func main() {
 		log.Fatalf("Error opening database: %q", err)
 	}
 
+	// Replace the built in achievement rules if a file of them is given
+	if rulesPath := os.Getenv("ACHIEVEMENT_RULES"); rulesPath != "" {
+		rulesFile, err := os.Open(rulesPath)
+		if err != nil {
+			log.Fatalf("Error opening achievement rules: %q", err)
+		}
+
+		data.AchievementRules, err = data.ParseAchievementRules(rulesFile)
+		rulesFile.Close()
+		if err != nil {
+			log.Fatalf("Error reading achievement rules: %q", err)
+		}
+	}
+
+	// Set up where uploaded pictures are stored
+	var pictureStore storage.Store
+	switch os.Getenv("STORAGE") {
+	case "s3":
+		pictureStore = storage.NewS3(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PublicURL:       os.Getenv("S3_PUBLIC_URL"),
+		}, nil)
+	default:
+		pictureDir := os.Getenv("PICTURE_DIR")
+		if pictureDir == "" {
+			pictureDir = "pictures"
+		}
+
+		pictureStore, err = storage.NewLocal(pictureDir, "/pictures")
+		if err != nil {
+			log.Fatalf("Error opening picture directory: %q", err)
+		}
+	}
+
+	// Set up reading WODs from whiteboard pictures
+	ocrEngine := ocr.NewTesseract(os.Getenv("TESSERACT_PATH"), os.Getenv("TESSERACT_LANGUAGE"))
+
+	// Set up telling users when someone interacts with their results
+	var notifier notify.Notifier = notify.Log{}
+
+	// Set up sending emails (written to a directory unless an SMTP server is given)
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "wodland <no-reply@wodland.app>"
+	}
+
+	var mailer mail.Mailer
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		mailer = mail.NewSMTP(smtpAddr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	} else {
+		mailDir := os.Getenv("MAIL_DIR")
+		if mailDir == "" {
+			mailDir = "mail"
+		}
+
+		mailer, err = mail.NewFileDrop(mailDir, mailFrom)
+		if err != nil {
+			log.Fatalf("Error opening mail directory: %q", err)
+		}
+	}
+
+	accountMail := http.Mail{Mailer: mailer, AppURL: os.Getenv("APP_URL")}
+
+	// Limit password reset and verification emails (per address, client and user)
+	accountEmailLimiter := ratelimit.New(5, time.Hour)
+
+	// Set up logging in with a single sign-on provider (OIDC_ISSUER=mock runs a local provider that logs everyone
+	// in as a test athlete, for trying it out, in builds with -tags mockoidc)
+	var sso *http.SSO
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		ssoConfig := oidc.Config{
+			Issuer:       issuer,
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv("OIDC_SCOPES")),
+		}
+
+		if issuer == "mock" {
+			stopMockIssuer, err := startMockIssuer(&ssoConfig, port)
+			if err != nil {
+				log.Fatalf("Error starting mock OIDC issuer: %q", err)
+			}
+			defer stopMockIssuer()
+		}
+
+		if ssoConfig.ClientID == "" || ssoConfig.RedirectURL == "" {
+			log.Fatal("$OIDC_CLIENT_ID and $OIDC_REDIRECT_URL must be set to use $OIDC_ISSUER")
+		}
+
+		sso = &http.SSO{
+			Provider:      oidc.NewProvider(ssoConfig, &httpImport.Client{Timeout: 30 * time.Second}),
+			AutoProvision: os.Getenv("OIDC_AUTO_PROVISION") != "false",
+		}
+	}
+
+	// Set up slowing down and locking out password guessing (counts are kept in the database so every instance
+	// shares them, unless LOGIN_ATTEMPTS=memory)
+	var loginAttempts lockout.Store = lockout.NewPostgres(dataSource)
+	if os.Getenv("LOGIN_ATTEMPTS") == "memory" {
+		loginAttempts = lockout.NewMemory()
+	}
+	loginGuard := lockout.NewGuard(loginAttempts)
+	loginGuard.OnEvent = http.AuditLoginEvents(dataSource)
+
+	// Work out the client's IP from the X-Forwarded-For entries added by the proxies in front of the service
+	// (Heroku's router adds one), never the ones the client sent, so failed logins and rate limits can't be
+	// spread over made up addresses
+	proxyHops := 0
+	if _, onHeroku := os.LookupEnv("DYNO"); onHeroku {
+		proxyHops = 1
+	}
+	if hops := os.Getenv("TRUSTED_PROXY_HOPS"); hops != "" {
+		if proxyHops, err = strconv.Atoi(hops); err != nil || proxyHops < 0 {
+			log.Fatal("$TRUSTED_PROXY_HOPS must be a number of proxies")
+		}
+	}
+
 	router := gin.New()
+	router.ForwardedByClientIP = false
+	router.Use(http.ClientIP(proxyHops))
 	router.Use(gin.Logger())
+	router.Use(http.RequestID())
+
+	// Pictures are served to anyone with their URL (so they work in img tags), which have a random part so
+	// pictures of WODs that aren't public can't be found by guessing (directory listings are off)
+	if local, ok := pictureStore.(*storage.Local); ok {
+		router.Static("/pictures", local.Dir)
+	}
 
 	// The jwt middleware
 	authMiddleware, err := jwt.New(&jwt.GinJWTMiddleware{
 		Realm:       "test zone",
 		Key:         []byte("secret key"),
-		Timeout:     time.Hour,
-		MaxRefresh:  time.Hour,
+		Timeout:     data.AccessTokenTTL,
 		IdentityKey: usernameKey,
 		PayloadFunc: func(dataInterface interface{}) jwt.MapClaims {
 			if v, ok := dataInterface.(*data.User); ok {
+				// Every token gets an ID so it can be denied after logging out (one that fails to get one
+				// is turned away by http.RequireAuth)
+				tokenID, _, _ := data.NewToken()
 				return jwt.MapClaims{
 					idKey:       v.ID,
 					usernameKey: v.Username,
 					roleKey:     v.Role,
+					sessionKey:  v.Session,
+					tokenIDKey:  tokenID,
 				}
 			}
 			return jwt.MapClaims{}
 		},
 		IdentityHandler: func(c *gin.Context) interface{} {
 			claims := jwt.ExtractClaims(c)
+			session, _ := claims[sessionKey].(string)
 			return &data.User{
 				ID:       int(claims[idKey].(float64)),
 				Username: claims[usernameKey].(string),
 				Role:     claims[roleKey].(string),
+				Session:  session,
 			}
 		},
-		Authenticator: func(c *gin.Context) (interface{}, error) {
-			var loginVals data.Login
-			if err := c.ShouldBind(&loginVals); err != nil {
-				return "", jwt.ErrMissingLoginValues
-			}
-
-			// Fetch login user
-			user, err := db.GetUser(dataSource, loginVals)
-			if err != nil {
-				fmt.Printf("fetching user err: %v \n", err)
-				return nil, jwt.ErrFailedAuthentication
-			}
-
-			return &user, nil
-		},
+		Authenticator: http.Authenticate(dataSource, loginGuard),
 		Authorizator: func(dataInterface interface{}, c *gin.Context) bool {
 			if _, ok := dataInterface.(*data.User); ok {
 				return true
@@ -105,11 +219,13 @@ func main() {
 			return false
 		},
 		Unauthorized: func(c *gin.Context, code int, message string) {
+			code = http.LoginStatus(c, code)
 			c.JSON(code, gin.H{
 				"code":    code,
 				"message": message,
 			})
 		},
+		LoginResponse: http.LoginResponse,
 		TokenLookup:   "header: Authorization, query: token, cookie: jwt",
 		TokenHeadName: "Bearer",
 		TimeFunc:      time.Now,
@@ -124,30 +240,242 @@ func main() {
 		log.Fatal("authMiddleware.MiddlewareInit() Error:" + errInit.Error())
 	}
 
+	// Every authenticated endpoint checks the access token hasn't been revoked as well as that it's valid
+	authRequired := http.RequireAuth(authMiddleware, dataSource)
+
+	// Endpoints scripts and integrations can also call with an API key that has the scope
+	readActivities := http.RequireAuth(authMiddleware, dataSource, data.ScopeActivitiesRead)
+	writeActivities := http.RequireAuth(authMiddleware, dataSource, data.ScopeActivitiesWrite)
+	readWODs := http.RequireAuth(authMiddleware, dataSource, data.ScopeWODsRead)
+
+	// Endpoints to log in, swap a refresh token for new tokens and log out (of this session or all of them)
 	router.POST("/login", authMiddleware.LoginHandler)
-	router.POST("/logout", authMiddleware.LogoutHandler)
-	router.GET("/refresh_token", authMiddleware.RefreshHandler)
-	router.GET("/hello", authMiddleware.MiddlewareFunc(), helloHandler) // Test endpoint delete when done
-	router.NoRoute(authMiddleware.MiddlewareFunc(), func(c *gin.Context) {
+	router.POST("/refresh_token", http.RefreshToken(dataSource, authMiddleware))
+	router.POST("/logout", authRequired, http.Logout(dataSource))
+	router.POST("/logout/all", authRequired, http.LogoutEverywhere(dataSource))
+
+	// Endpoints to log in with the single sign-on provider and link, list and unlink identities from it
+	if sso != nil {
+		router.GET("/login/oidc", http.StartOIDCLogin(dataSource, *sso))
+		router.GET("/login/oidc/callback", http.OIDCCallback(dataSource, *sso, authMiddleware))
+		router.POST("/me/identities", authRequired, http.LinkIdentity(dataSource, *sso))
+		router.GET("/me/identities", authRequired, http.GetIdentities(dataSource))
+		router.DELETE("/me/identities/:identityID", authRequired, http.UnlinkIdentity(dataSource))
+	}
+
+	router.NoRoute(authRequired, func(c *gin.Context) {
 		claims := jwt.ExtractClaims(c)
 		log.Printf("NoRoute claims: %#v\n", claims)
 		c.JSON(404, gin.H{"code": "PAGE_NOT_FOUND", "message": "Page not found"})
 	})
 
+	// Endpoints to create, list and revoke API keys for scripts and integrations
+	router.POST("/APIKey", authRequired, http.AddAPIKey(dataSource))
+	router.GET("/APIKeys", authRequired, http.GetAPIKeys(dataSource))
+	router.DELETE("/APIKey/:keyID", authRequired, http.RevokeAPIKey(dataSource))
+
+	// Endpoints to get and change the user's profile and delete their account
+	router.GET("/me", authRequired, http.GetMe(dataSource))
+	router.PATCH("/me", authRequired, http.UpdateMe(dataSource, accountMail))
+	router.DELETE("/me", authRequired, http.DeleteMe(dataSource, pictureStore))
+
+	// Endpoint to change the user's password (checking their current one)
+	router.PUT("/me/password", authRequired, http.ChangePassword(dataSource))
+
+	// Endpoints to email a password reset link and reset the password with it
+	router.POST("/password/reset", http.RequestPasswordReset(dataSource, accountMail, accountEmailLimiter))
+	router.POST("/password/reset/confirm", http.ResetPassword(dataSource))
+
+	// Endpoints to email a link to verify the user's email address and verify it with the link
+	router.POST("/me/email/verification", authRequired, http.SendEmailVerification(dataSource, accountMail, accountEmailLimiter))
+	router.POST("/email/verify", http.VerifyEmail(dataSource))
+
 	// Endpoint to get single WOD and any attempts at it
-	router.GET("/WOD/:wodID", authMiddleware.MiddlewareFunc(), http.GetWOD(dataSource))
+	router.GET("/WOD/:wodID", readWODs, http.GetWOD(dataSource))
+
+	// Endpoint to draft a WOD description from a picture of a whiteboard
+	router.POST("/WODPreview", authRequired, http.PreviewWOD(ocrEngine))
+
+	// Endpoint to upload a WOD's picture
+	router.POST("/WOD/:wodID/picture", authRequired, http.UploadWODPicture(dataSource, pictureStore))
+
+	// Endpoints to change who can see a WOD and to create or revoke links sharing it
+	router.PUT("/WOD/:wodID/visibility", authRequired, http.UpdateWODVisibility(dataSource))
+	router.POST("/WOD/:wodID/share", authRequired, http.ShareWOD(dataSource))
+	router.DELETE("/WOD/:wodID/share", authRequired, http.RevokeWODShares(dataSource))
+
+	// Endpoint to get a WOD from a share link (and keep access to it)
+	router.GET("/SharedWOD/:token", authRequired, http.GetSharedWOD(dataSource))
 
 	// Endpoint to get WODs (can be filtered)
-	router.GET("/WODs", authMiddleware.MiddlewareFunc(), http.GetWODs(dataSource))
+	router.GET("/WODs", readWODs, http.GetWODs(dataSource))
 
 	// Endpoint to get WODs (can be filtered)
-	router.GET("/Activities", authMiddleware.MiddlewareFunc(), http.GetActivities(dataSource))
+	router.GET("/Activities", readActivities, http.GetActivities(dataSource))
 
 	// Endpoint to create a WOD (and add an attempt if supplied)
-	router.POST("/WOD", authMiddleware.MiddlewareFunc(), http.AddWOD(dataSource))
+	router.POST("/WOD", authRequired, http.AddWOD(dataSource))
 
 	// Endpoint to add an Activity
-	router.POST("/Activity", authMiddleware.MiddlewareFunc(), http.AddActivity(dataSource))
+	router.POST("/Activity", writeActivities, http.AddActivity(dataSource))
+
+	// Endpoint to add an Activity from a FIT, TCX or GPX file (with its heart rate)
+	router.POST("/ActivityFile", writeActivities, http.AddActivityFromFile(dataSource))
+
+	// Endpoints to add the heart rate from a FIT, TCX or GPX file to an Activity and get it
+	router.POST("/Activity/:activityID/heartRate", writeActivities, http.UploadHeartRate(dataSource))
+	router.GET("/Activity/:activityID/heartRate", readActivities, http.GetHeartRate(dataSource))
+
+	// Endpoints to follow (or ask to follow) other users and stop following them
+	router.POST("/Follow/:userID", authRequired, http.FollowUser(dataSource))
+	router.DELETE("/Follow/:userID", authRequired, http.UnfollowUser(dataSource))
+
+	// Endpoints to get who the user follows, who follows them and who is asking to
+	router.GET("/Following", authRequired, http.GetFollowing(dataSource))
+	router.GET("/Followers", authRequired, http.GetFollowers(dataSource))
+	router.GET("/FollowRequests", authRequired, http.GetFollowRequests(dataSource))
+
+	// Endpoints to accept a follow request and to remove a follower (or turn down their request)
+	router.PUT("/Follower/:userID", authRequired, http.AcceptFollower(dataSource))
+	router.DELETE("/Follower/:userID", authRequired, http.RemoveFollower(dataSource))
+
+	// Endpoints to block and unblock other users and get who is blocked
+	router.POST("/Block/:userID", authRequired, http.BlockUser(dataSource))
+	router.DELETE("/Block/:userID", authRequired, http.UnblockUser(dataSource))
+	router.GET("/Blocked", authRequired, http.GetBlocked(dataSource))
+
+	// Endpoints to get and change whether followers have to be accepted
+	router.GET("/Privacy", authRequired, http.GetPrivacy(dataSource))
+	router.PUT("/Privacy", authRequired, http.UpdatePrivacy(dataSource))
+
+	// Endpoint to get recent Activities by the user, people they follow and people they share a gym with
+	router.GET("/Feed", authRequired, http.GetFeed(dataSource))
+
+	// Endpoints to comment on Activities and WODs and get the comments on them
+	router.POST("/Activity/:activityID/comments", authRequired, http.AddComment(dataSource, notifier))
+	router.GET("/Activity/:activityID/comments", authRequired, http.GetComments(dataSource))
+	router.POST("/WOD/:wodID/comments", authRequired, http.AddComment(dataSource, notifier))
+	router.GET("/WOD/:wodID/comments", authRequired, http.GetComments(dataSource))
+
+	// Endpoint to delete a comment (by its author or the owner of what it's on)
+	router.DELETE("/Comment/:commentID", authRequired, http.DeleteComment(dataSource))
+
+	// Endpoints to react to Activities and WODs and take reactions back
+	router.PUT("/Activity/:activityID/reactions/:kind", authRequired, http.AddReaction(dataSource))
+	router.DELETE("/Activity/:activityID/reactions/:kind", authRequired, http.RemoveReaction(dataSource))
+	router.PUT("/WOD/:wodID/reactions/:kind", authRequired, http.AddReaction(dataSource))
+	router.DELETE("/WOD/:wodID/reactions/:kind", authRequired, http.RemoveReaction(dataSource))
+
+	// Endpoints to get and change the max heart rate zones are worked out from
+	router.GET("/MaxHeartRate", authRequired, http.GetMaxHeartRate(dataSource))
+	router.PUT("/MaxHeartRate", authRequired, http.UpdateMaxHeartRate(dataSource))
+
+	// Endpoint to export all of a user's Activities (csv, json or ndjson)
+	router.GET("/export", readActivities, http.ExportActivities(dataSource))
+
+	// Endpoint to import Activities (and WODs) from a CSV file or another tracker's export
+	router.POST("/import", writeActivities, http.ImportActivities(dataSource))
+
+	// Endpoints to plan sessions and get, change or delete them
+	router.POST("/PlannedSession", authRequired, http.AddPlannedSession(dataSource))
+	router.GET("/PlannedSession/:plannedID", authRequired, http.GetPlannedSession(dataSource))
+	router.PUT("/PlannedSession/:plannedID", authRequired, http.UpdatePlannedSession(dataSource))
+	router.DELETE("/PlannedSession/:plannedID", authRequired, http.DeletePlannedSession(dataSource))
+
+	// Endpoint to get planned sessions (can be filtered)
+	router.GET("/PlannedSessions", authRequired, http.GetPlannedSessions(dataSource))
+
+	// Endpoint to get the sessions planned for today
+	router.GET("/Today", authRequired, http.GetToday(dataSource))
+
+	// Endpoint to compare planned sessions with those completed
+	router.GET("/Adherence", authRequired, http.GetAdherence(dataSource))
+
+	// Endpoints to get and change the units a user sees loads and distances in
+	router.GET("/Units", authRequired, http.GetUnits(dataSource))
+	router.PUT("/Units", authRequired, http.UpdateUnits(dataSource))
+
+	// Endpoints to record body metrics and benchmark tests and get, change or delete them
+	router.POST("/Metric", authRequired, http.AddMetric(dataSource))
+	router.GET("/Metric/:metricID", authRequired, http.GetMetric(dataSource))
+	router.PUT("/Metric/:metricID", authRequired, http.UpdateMetric(dataSource))
+	router.DELETE("/Metric/:metricID", authRequired, http.DeleteMetric(dataSource))
+
+	// Endpoints to get metrics (can be filtered) and how they've changed
+	router.GET("/Metrics", authRequired, http.GetMetrics(dataSource))
+	router.GET("/MetricTrends", authRequired, http.GetMetricTrends(dataSource))
+
+	// Endpoint to get the badges a user has earned and their progress towards the rest
+	router.GET("/achievements", authRequired, http.GetAchievements(dataSource))
+
+	// Endpoints to set goals and get, change or delete them
+	router.POST("/Goal", authRequired, http.AddGoal(dataSource))
+	router.GET("/Goal/:goalID", authRequired, http.GetGoal(dataSource))
+	router.PUT("/Goal/:goalID", authRequired, http.UpdateGoal(dataSource))
+	router.DELETE("/Goal/:goalID", authRequired, http.DeleteGoal(dataSource))
+
+	// Endpoints to get goals with their progress and a summary of them
+	router.GET("/Goals", authRequired, http.GetGoals(dataSource))
+	router.GET("/GoalSummary", authRequired, http.GetGoalSummary(dataSource))
+
+	// Endpoints to log and delete lifts
+	router.POST("/Lift", authRequired, http.AddLift(dataSource))
+	router.DELETE("/Lift/:liftID", authRequired, http.DeleteLift(dataSource))
+
+	// Endpoint to get lift history (can be filtered)
+	router.GET("/Lifts", authRequired, http.GetLifts(dataSource))
+
+	// Endpoints to get lift PRs and percentages of a movement's estimated one rep max
+	router.GET("/LiftPRs", authRequired, http.GetLiftPRs(dataSource))
+	router.GET("/LiftPercentages", authRequired, http.GetLiftPercentages(dataSource))
+
+	// Endpoints for admins to see and clear failed logins to an account or from a client IP
+	admin := http.RequireRole(data.RoleAdmin)
+	router.GET("/Lockout/:scope/:subject", authRequired, admin, http.GetLoginLockout(loginGuard))
+	router.DELETE("/Lockout/:scope/:subject", authRequired, admin, http.UnlockLogin(loginGuard))
+
+	// Endpoint for admins to search the audit log
+	router.GET("/AuditLog", authRequired, admin, http.GetAuditLog(dataSource))
+
+	// Endpoints for coaches to create and assign programs
+	coach := http.RequireRole(data.RoleCoach, data.RoleAdmin)
+	router.POST("/Program", authRequired, coach, http.AddProgram(dataSource))
+	router.POST("/Program/:programID/assign", authRequired, coach, http.AssignProgram(dataSource))
+
+	// Endpoints to get programs (created or assigned) and progress through them
+	router.GET("/Program/:programID", authRequired, http.GetProgram(dataSource))
+	router.GET("/Program/:programID/progress", authRequired, http.GetProgramProgress(dataSource))
+	router.GET("/Programs", authRequired, http.GetPrograms(dataSource))
+
+	// Endpoint to get the program workouts assigned for a day (defaults to today)
+	router.GET("/Programming", authRequired, http.GetProgramming(dataSource))
+
+	// Endpoints for coaches to manage groups of athletes
+	router.POST("/Group", authRequired, coach, http.AddGroup(dataSource))
+	router.GET("/Groups", authRequired, coach, http.GetGroups(dataSource))
+	router.POST("/Group/:groupID/members", authRequired, coach, http.AddGroupMembers(dataSource))
+	router.DELETE("/Group/:groupID/member/:userID", authRequired, coach, http.RemoveGroupMember(dataSource))
+
+	// Endpoints to create gyms, join them with an invite code and list the user's gyms
+	router.POST("/Gym", authRequired, http.AddGym(dataSource))
+	router.POST("/JoinGym", authRequired, http.JoinGym(dataSource))
+	router.GET("/Gyms", authRequired, http.GetGyms(dataSource))
+
+	// Endpoints to manage a gym's invite code and members
+	router.POST("/Gym/:gymID/invite", authRequired, http.RotateInviteCode(dataSource))
+	router.GET("/Gym/:gymID/members", authRequired, http.GetGymMembers(dataSource))
+	router.PUT("/Gym/:gymID/member/:userID", authRequired, http.UpdateGymMemberRole(dataSource))
+	router.DELETE("/Gym/:gymID/member/:userID", authRequired, http.RemoveGymMember(dataSource))
+
+	// Endpoint to get a gym's leaderboard for a WOD
+	router.GET("/Gym/:gymID/leaderboard/:wodID", authRequired, http.GetLeaderboard(dataSource))
+
+	// Endpoints to create (or rotate) and revoke the token for a user's calendar feed
+	router.POST("/calendar/token", authRequired, http.CreateCalendarToken(dataSource))
+	router.DELETE("/calendar/token", authRequired, http.RevokeCalendarToken(dataSource))
+
+	// Endpoint to get a user's iCalendar feed (authenticated by the token in the URL)
+	router.GET("/calendar/:token", http.GetCalendar(dataSource))
 
 	if err := httpImport.ListenAndServe(":"+port, router); err != nil {
 		log.Fatal(err)
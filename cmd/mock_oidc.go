@@ -0,0 +1,28 @@
+//go:build mockoidc
+// +build mockoidc
+
+package main
+
+import (
+	"log"
+
+	"github.com/philLITERALLY/wodland-service/internal/oidc"
+	"github.com/philLITERALLY/wodland-service/internal/oidc/oidctest"
+)
+
+// startMockIssuer runs a local provider that logs everyone in as a test athlete (for trying single sign-on out)
+// and points config at it. It's only built with the mockoidc tag, so it can't be turned on in production.
+func startMockIssuer(config *oidc.Config, port string) (func(), error) {
+	mockIssuer, err := oidctest.NewIssuer("wodland", "mock-secret")
+	if err != nil {
+		return nil, err
+	}
+
+	config.Issuer, config.ClientID, config.ClientSecret = mockIssuer.URL, mockIssuer.ClientID, mockIssuer.ClientSecret
+	if config.RedirectURL == "" {
+		config.RedirectURL = "http://localhost:" + port + "/login/oidc/callback"
+	}
+	log.Printf("Mock OIDC issuer running at %s", mockIssuer.URL)
+
+	return mockIssuer.Close, nil
+}
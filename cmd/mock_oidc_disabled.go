@@ -0,0 +1,15 @@
+//go:build !mockoidc
+// +build !mockoidc
+
+package main
+
+import (
+	"errors"
+
+	"github.com/philLITERALLY/wodland-service/internal/oidc"
+)
+
+// startMockIssuer fails, as the mock provider is only built with the mockoidc tag
+func startMockIssuer(config *oidc.Config, port string) (func(), error) {
+	return nil, errors.New("the mock issuer is only available when built with -tags mockoidc")
+}
@@ -0,0 +1,161 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// What achievement rules count
+const (
+	AchievementActivities   = "activities"
+	AchievementPRs          = "prs"
+	AchievementStreak       = "streak"
+	AchievementDistinctWODs = "distinctWODs"
+)
+
+// AchievementRule declares a badge: it's earned once the count of Metric reaches Target. Activities, PRs (beating
+// a previous best time on a WOD) and distinct WODs can be limited to WODs of WODType; streaks are consecutive days
+// with an Activity.
+type AchievementRule struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Metric      string `json:"metric"`
+	Target      int64  `json:"target"`
+	WODType     string `json:"wodType,omitempty"`
+}
+
+// AchievementRules are the badges that can be earned
+var AchievementRules = []AchievementRule{
+	{ID: "first-activity", Name: "First Blood", Description: "Log your first Activity", Metric: AchievementActivities, Target: 1},
+	{ID: "activities-50", Name: "Regular", Description: "Log 50 Activities", Metric: AchievementActivities, Target: 50},
+	{ID: "activities-250", Name: "Lifer", Description: "Log 250 Activities", Metric: AchievementActivities, Target: 250},
+	{ID: "first-pr", Name: "Personal Best", Description: "Beat your best time on a WOD", Metric: AchievementPRs, Target: 1},
+	{ID: "prs-25", Name: "Record Breaker", Description: "Set 25 PRs", Metric: AchievementPRs, Target: 25},
+	{ID: "streak-7", Name: "Week Streak", Description: "Train 7 days in a row", Metric: AchievementStreak, Target: 7},
+	{ID: "streak-30", Name: "Month Streak", Description: "Train 30 days in a row", Metric: AchievementStreak, Target: 30},
+	{ID: "girls-5", Name: "Ladies' Night", Description: "Complete 5 different Girls WODs", Metric: AchievementDistinctWODs, Target: 5, WODType: "Girls"},
+	{ID: "heroes-10", Name: "Hero", Description: "Complete 10 different Hero WODs", Metric: AchievementDistinctWODs, Target: 10, WODType: "Hero"},
+}
+
+// ParseAchievementRules reads a JSON list of achievement rules, checking each is valid
+func ParseAchievementRules(r io.Reader) ([]AchievementRule, error) {
+	var rules []AchievementRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return nil, err
+	}
+
+	ids := map[string]bool{}
+	for _, rule := range rules {
+		switch {
+		case rule.ID == "" || ids[rule.ID]:
+			return nil, fmt.Errorf("achievement rule IDs must be given and unique ('%s')", rule.ID)
+		case rule.Target < 1:
+			return nil, fmt.Errorf("achievement rule '%s' needs a target", rule.ID)
+		}
+
+		switch rule.Metric {
+		case AchievementActivities, AchievementPRs, AchievementStreak, AchievementDistinctWODs:
+		default:
+			return nil, fmt.Errorf("achievement rule '%s' has unknown metric '%s'", rule.ID, rule.Metric)
+		}
+
+		ids[rule.ID] = true
+	}
+
+	return rules, nil
+}
+
+// AchievementEvent is an Activity achievements are evaluated against
+type AchievementEvent struct {
+	Date      int64
+	WODID     int
+	WODType   string
+	TimeTaken int64
+}
+
+// Achievement is a badge and how close the user is to earning it
+type Achievement struct {
+	AchievementRule
+	Progress int64  `json:"progress"`
+	Earned   bool   `json:"earned"`
+	EarnedAt *int64 `json:"earnedAt,omitempty"`
+}
+
+// EvaluateAchievements replays events (oldest first) against rules, returning each badge with progress towards it
+// and when it was earned. Replaying the whole history means evaluating again always gives the same result. Streaks
+// count days in loc (the user's time zone).
+func EvaluateAchievements(rules []AchievementRule, events []AchievementEvent, loc *time.Location) []Achievement {
+	achievements := make([]Achievement, len(rules))
+	for i, rule := range rules {
+		achievements[i].AchievementRule = rule
+	}
+
+	best := map[int]int64{}
+	seen := make([]map[int]bool, len(rules))
+	var streak, lastDay int64
+
+	for _, event := range events {
+		previous, tried := best[event.WODID]
+		pr := tried && event.TimeTaken < previous
+		if !tried || pr {
+			best[event.WODID] = event.TimeTaken
+		}
+
+		day := StartOfDay(event.Date, loc)
+		switch {
+		case streak > 0 && day == lastDay:
+		case streak > 0 && day == NextDay(lastDay, loc):
+			streak++
+		default:
+			streak = 1
+		}
+		lastDay = day
+
+		for i := range achievements {
+			achievement := &achievements[i]
+			if achievement.Metric != AchievementStreak && achievement.WODType != "" &&
+				!strings.EqualFold(achievement.WODType, event.WODType) {
+				continue
+			}
+
+			switch achievement.Metric {
+			case AchievementActivities:
+				achievement.Progress++
+			case AchievementPRs:
+				if pr {
+					achievement.Progress++
+				}
+			case AchievementStreak:
+				if streak > achievement.Progress {
+					achievement.Progress = streak
+				}
+			case AchievementDistinctWODs:
+				if seen[i] == nil {
+					seen[i] = map[int]bool{}
+				}
+				if !seen[i][event.WODID] {
+					seen[i][event.WODID] = true
+					achievement.Progress++
+				}
+			}
+
+			if !achievement.Earned && achievement.Progress >= achievement.Target {
+				date := event.Date
+				achievement.Earned = true
+				achievement.EarnedAt = &date
+			}
+		}
+	}
+
+	for i := range achievements {
+		if achievements[i].Progress > achievements[i].Target {
+			achievements[i].Progress = achievements[i].Target
+		}
+	}
+
+	return achievements
+}
@@ -0,0 +1,42 @@
+package data
+
+// Scopes an API key can be given
+const (
+	ScopeActivitiesRead  = "activities:read"
+	ScopeActivitiesWrite = "activities:write"
+	ScopeWODsRead        = "wods:read"
+)
+
+// APIKeyPrefix starts every API key so they're easy to recognise (e.g. by secret scanners)
+const APIKeyPrefix = "wl_"
+
+// MaxAPIKeys is how many unrevoked API keys a user can have
+const MaxAPIKeys = 20
+
+// ValidScope checks scope is one an API key can be given
+func ValidScope(scope string) bool {
+	switch scope {
+	case ScopeActivitiesRead, ScopeActivitiesWrite, ScopeWODsRead:
+		return true
+	}
+	return false
+}
+
+// HasScopes checks every one of required is in granted
+func HasScopes(granted []string, required []string) bool {
+	for _, scope := range required {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,37 @@
+package data
+
+// Actions recorded in the audit log
+const (
+	AuditLogin          = "login"
+	AuditLoginFailed    = "login_failed"
+	AuditLoginBlocked   = "login_blocked"
+	AuditLoginLockout   = "login_lockout"
+	AuditLoginUnlock    = "login_unlock"
+	AuditPasswordChange = "password_change"
+	AuditPasswordReset  = "password_reset"
+	AuditRoleChange     = "role_change"
+	AuditCreate         = "create"
+	AuditUpdate         = "update"
+	AuditDelete         = "delete"
+	AuditImport         = "import"
+)
+
+// Kinds of resource recorded in the audit log
+const (
+	ResourceUser       = "user"
+	ResourceLogin      = "login"
+	ResourceGymMember  = "gym_member"
+	ResourceWOD        = "wod"
+	ResourceWODShare   = "wod_share"
+	ResourceActivity   = "activity"
+	ResourceActivities = "activities"
+)
+
+// ValidResourceType checks resourceType is a kind of resource recorded in the audit log
+func ValidResourceType(resourceType string) bool {
+	switch resourceType {
+	case ResourceUser, ResourceLogin, ResourceGymMember, ResourceWOD, ResourceWODShare, ResourceActivity, ResourceActivities:
+		return true
+	}
+	return false
+}
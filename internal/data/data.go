@@ -2,6 +2,7 @@ package data
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -13,6 +14,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+	"github.com/philLITERALLY/wodland-service/internal/wearable"
 )
 
 // Login is the data required for a user to login
@@ -27,58 +30,577 @@ type User struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Role     string `json:"role"`
+	// Session is the login session the User's token belongs to
+	Session string `json:"-"`
+}
+
+// APIKeyInput is the data required to create an API key
+type APIKeyInput struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt *int64   `json:"expiresAt"`
+}
+
+// APIKey is the data object returned for an API key (the key itself is only returned when it's created)
+type APIKey struct {
+	ID         int64    `json:"id"`
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	CreatedAt  int64    `json:"createdAt"`
+	LastUsedAt *int64   `json:"lastUsedAt"`
+	ExpiresAt  *int64   `json:"expiresAt"`
+}
+
+// RefreshInput is the data required to swap a refresh token for a new access token
+type RefreshInput struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// LoginLockout is the data object returned for the failed logins to an account or from a client IP
+type LoginLockout struct {
+	Scope         string `json:"scope"`
+	Subject       string `json:"subject"`
+	Failures      int    `json:"failures"`
+	LastFailureAt *int64 `json:"lastFailureAt"`
+	BlockedUntil  *int64 `json:"blockedUntil"`
+	// Blocked is whether logging in is being turned away now
+	Blocked bool `json:"blocked"`
+}
+
+// AuditEntry is the data object for an entry in the audit log. Before and After are snapshots of the resource
+// (or the parts of it that changed) as JSON.
+type AuditEntry struct {
+	ID           int64           `json:"id"`
+	ActorID      *int            `json:"actorID"`
+	Actor        string          `json:"actor"`
+	Action       string          `json:"action"`
+	ResourceType string          `json:"resourceType"`
+	ResourceID   string          `json:"resourceID"`
+	Before       json.RawMessage `json:"before"`
+	After        json.RawMessage `json:"after"`
+	IP           string          `json:"ip"`
+	RequestID    string          `json:"requestID"`
+	CreatedAt    int64           `json:"createdAt"`
+}
+
+// AuditPage is a page of the audit log, newest first, with the cursor for the next page (if there is one)
+type AuditPage struct {
+	Items []AuditEntry `json:"items"`
+	Next  *string      `json:"next"`
+}
+
+// OIDCLogin is a login with the single sign-on provider waiting for the user to come back from it
+type OIDCLogin struct {
+	Nonce        string
+	CodeVerifier string
+	// LinkUserID is the User linking an identity to their account (nil when logging in)
+	LinkUserID *int
+}
+
+// Identity is the data object returned for a single sign-on identity linked to a User
+type Identity struct {
+	ID          int64   `json:"id"`
+	Issuer      string  `json:"issuer"`
+	Subject     string  `json:"subject"`
+	Email       *string `json:"email"`
+	CreatedAt   int64   `json:"createdAt"`
+	LastLoginAt *int64  `json:"lastLoginAt"`
+}
+
+// Profile is the data object returned for the logged in User
+type Profile struct {
+	ID            int          `json:"userID"`
+	Username      string       `json:"username"`
+	Role          string       `json:"role"`
+	Email         *string      `json:"email"`
+	EmailVerified bool         `json:"emailVerified"`
+	DisplayName   *string      `json:"displayName"`
+	Avatar        *string      `json:"avatar"`
+	TimeZone      string       `json:"timeZone"`
+	Units         units.System `json:"units"`
+	BirthYear     *int         `json:"birthYear"`
+	Division      *string      `json:"division"`
+}
+
+// ProfileInput is the data that can be changed on a User's profile (fields left out stay as they are, and an
+// empty display name, avatar or division clears it). Changing the email address means it has to be verified again.
+type ProfileInput struct {
+	Email       *string       `json:"email"`
+	DisplayName *string       `json:"displayName"`
+	Avatar      *string       `json:"avatar"`
+	TimeZone    *string       `json:"timeZone"`
+	Units       *units.System `json:"units"`
+	BirthYear   *int          `json:"birthYear"`
+	Division    *string       `json:"division"`
+}
+
+// PasswordChange is the data required to change a User's password
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// PasswordResetRequest is the data required to ask for a password reset email
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+// PasswordReset is the data required to reset a password with the token from a password reset email
+type PasswordReset struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"newPassword"`
+}
+
+// EmailVerification is the data required to verify an email address with the token from a verification email
+type EmailVerification struct {
+	Token string `json:"token"`
+}
+
+// Roles a User can have
+const (
+	RoleAthlete = "athlete"
+	RoleCoach   = "coach"
+	RoleAdmin   = "admin"
+)
+
+// Roles a User can have within a Gym
+const (
+	GymRoleOwner   = "owner"
+	GymRoleCoach   = "coach"
+	GymRoleAthlete = "athlete"
+)
+
+// GymInput is the data required to create a Gym
+type GymInput struct {
+	Name string `json:"name"`
+}
+
+// Gym is the data object returned for a Gym the user is a member of
+type Gym struct {
+	ID         int     `json:"id"`
+	Name       string  `json:"name"`
+	Role       string  `json:"role"`
+	InviteCode *string `json:"inviteCode,omitempty"`
+}
+
+// GymMember is a User's membership of a Gym
+type GymMember struct {
+	UserID   int    `json:"userID"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// Connection is another User the user follows, is followed by or has blocked
+type Connection struct {
+	UserID   int    `json:"userID"`
+	Username string `json:"username"`
+	Status   string `json:"status,omitempty"`
+	Since    int64  `json:"since"`
+}
+
+// Privacy is whether a User has to accept people before they can follow them
+type Privacy struct {
+	Private bool `json:"private"`
+}
+
+// LeaderboardEntry is a User's best result for a WOD
+type LeaderboardEntry struct {
+	Rank       int    `json:"rank"`
+	UserID     int    `json:"userID"`
+	Username   string `json:"username"`
+	ActivityID int64  `json:"activityID"`
+	Date       int64  `json:"date"`
+	TimeTaken  int64  `json:"timeTaken"`
+}
+
+// Who can see a WOD
+const (
+	VisibilityPrivate = "private"
+	VisibilityGym     = "gym"
+	VisibilityPublic  = "public"
+)
+
+// ValidVisibility checks visibility is one of the known values
+func ValidVisibility(visibility string) bool {
+	switch visibility {
+	case VisibilityPrivate, VisibilityGym, VisibilityPublic:
+		return true
+	}
+	return false
 }
 
 // WODInput is the data required to create a WOD
 type WODInput struct {
-	Source    *string `json:"source"`
-	CreationT int64   `json:"creationT"`
-	Exercise  *string `json:"exercise"`
-	Picture   *string `json:"picture"`
-	Type      string  `json:"type"`
+	Source     *string         `json:"source"`
+	CreationT  int64           `json:"creationT"`
+	Exercise   *string         `json:"exercise"`
+	Picture    *string         `json:"picture"`
+	Type       string          `json:"type"`
+	GymID      *int            `json:"gymID,omitempty"`
+	Visibility string          `json:"visibility,omitempty"`
+	Scaling    []ScalingOption `json:"scaling,omitempty"`
+}
+
+// Scaling tiers a WOD can be done at
+const (
+	TierRx          = "Rx"
+	TierScaled      = "Scaled"
+	TierFoundations = "Foundations"
+)
+
+// ValidTier checks tier is one of the known scaling tiers
+func ValidTier(tier string) bool {
+	switch tier {
+	case TierRx, TierScaled, TierFoundations:
+		return true
+	}
+	return false
+}
+
+// ScalingOption is how a WOD is done at a scaling tier. Distance is in metres, or calories for machine work.
+type ScalingOption struct {
+	Tier        string         `json:"tier"`
+	Load        *units.Measure `json:"load,omitempty"`
+	Distance    *units.Measure `json:"distance,omitempty"`
+	Loads       *string        `json:"loads,omitempty"`
+	Description *string        `json:"description,omitempty"`
+}
+
+// TierResult is a user's attempts at a WOD at one scaling tier
+type TierResult struct {
+	Tier     string `json:"tier"`
+	Attempts int    `json:"attempts"`
+	BestTime *int   `json:"bestTime,omitempty"`
 }
 
 // WOD is the data object returned from the WODs endpoint
 type WOD struct {
 	ID int `json:"id"`
 	WODInput
-	Attempts   *int        `json:"attempts"`
-	BestTime   *int        `json:"bestTime,omitempty"`
-	Activities *[]Activity `json:"activities,omitempty"`
+	Thumbnail   *string      `json:"thumbnail,omitempty"`
+	Attempts    *int         `json:"attempts"`
+	BestTime    *int         `json:"bestTime,omitempty"`
+	TierResults []TierResult `json:"tierResults,omitempty"`
+	Activities  *[]Activity  `json:"activities,omitempty"`
+}
+
+// WODPicture is a WOD's picture and thumbnail
+type WODPicture struct {
+	Picture   *string `json:"picture"`
+	Thumbnail *string `json:"thumbnail"`
 }
 
 // ActivityInput is the data required to create an Activity
 type ActivityInput struct {
-	Date      int64   `json:"date"`
-	WODID     *int    `json:"wodID,omitempty"`
-	TimeTaken int64   `json:"timeTaken"`
-	MEPs      *int64  `json:"meps,omitempty"`
-	Exertion  *int64  `json:"exertion,omitempty"`
-	Notes     *string `json:"notes,omitempty"`
+	Date          int64   `json:"date"`
+	WODID         *int    `json:"wodID,omitempty"`
+	TimeTaken     int64   `json:"timeTaken"`
+	MEPs          *int64  `json:"meps,omitempty"`
+	Exertion      *int64  `json:"exertion,omitempty"`
+	Notes         *string `json:"notes,omitempty"`
+	Tier          *string `json:"tier,omitempty"`
+	Substitutions *string `json:"substitutions,omitempty"`
 }
 
 // Activity is the data object returned for each activity
 type Activity struct {
-	ID int64 `json:"id"`
+	ID       int64  `json:"id"`
+	UserID   int    `json:"userID,omitempty"`
+	Username string `json:"username,omitempty"`
 	ActivityInput
 	WOD *WOD `json:"wod,omitempty"`
 }
 
+// HeartRate is the summary of the heart rate recorded during an Activity
+type HeartRate struct {
+	ActivityID int64 `json:"activityID"`
+	wearable.Summary
+}
+
+// FeedItem is an Activity in a user's feed, with who did it and how others have reacted
+type FeedItem struct {
+	Activity
+	Comments    int            `json:"comments"`
+	Reactions   map[string]int `json:"reactions"`
+	MyReactions []string       `json:"myReactions"`
+}
+
+// FeedPage is a page of a user's feed (Next is the cursor for the following page)
+type FeedPage struct {
+	Items []FeedItem `json:"items"`
+	Next  *string    `json:"next,omitempty"`
+}
+
+// CommentTarget is the Activity or WOD a comment or reaction is on
+type CommentTarget struct {
+	ActivityID *int64
+	WODID      *int
+}
+
+// CommentInput is the data required to comment on an Activity or WOD
+type CommentInput struct {
+	Body string `json:"body"`
+}
+
+// Comment is the data object returned for each comment
+type Comment struct {
+	ID         int64  `json:"id"`
+	UserID     int    `json:"userID"`
+	Username   string `json:"username"`
+	ActivityID *int64 `json:"activityID,omitempty"`
+	WODID      *int   `json:"wodID,omitempty"`
+	Body       string `json:"body"`
+	CreatedAt  int64  `json:"createdAt"`
+}
+
+// CommentPage is a page of comments, oldest first (Next is the cursor for the following page)
+type CommentPage struct {
+	Items     []Comment      `json:"items"`
+	Reactions map[string]int `json:"reactions"`
+	Next      *string        `json:"next,omitempty"`
+}
+
 // CreateWOD is the data object required to add a WOD
 type CreateWOD struct {
 	WODInput
 	*ActivityInput
 }
 
+// PlannedSessionInput is the data required to plan a session
+type PlannedSessionInput struct {
+	WODID       *int   `json:"wodID"`
+	PlannedDate int64  `json:"plannedDate"`
+	TargetScore *int64 `json:"targetScore,omitempty"`
+}
+
+// PlannedSession is the data object returned for each planned session
+type PlannedSession struct {
+	ID int64 `json:"id"`
+	PlannedSessionInput
+	// TimeZone is the time zone the session was planned in, which its date is midnight in
+	TimeZone   string `json:"timeZone"`
+	ActivityID *int64 `json:"activityID,omitempty"`
+	WOD        *WOD   `json:"wod,omitempty"`
+}
+
+// Adherence compares planned sessions with those completed
+type Adherence struct {
+	Planned   int     `json:"planned"`
+	Completed int     `json:"completed"`
+	Missed    int     `json:"missed"`
+	Upcoming  int     `json:"upcoming"`
+	Rate      float64 `json:"rate"`
+}
+
+// ProgramInput is the data required to create a program
+type ProgramInput struct {
+	Name        string        `json:"name"`
+	Description *string       `json:"description,omitempty"`
+	Weeks       []ProgramWeek `json:"weeks"`
+}
+
+// ProgramWeek is a week of a program
+type ProgramWeek struct {
+	Week int          `json:"week"`
+	Days []ProgramDay `json:"days"`
+}
+
+// ProgramDay is a day of a program week (1 to 7)
+type ProgramDay struct {
+	Day  int        `json:"day"`
+	WODs []WODInput `json:"wods"`
+}
+
+// Program is the data object returned for a program
+type Program struct {
+	ID          int              `json:"id"`
+	Name        string           `json:"name"`
+	Description *string          `json:"description,omitempty"`
+	CreatedBy   int              `json:"createdBy"`
+	Workouts    []ProgramWorkout `json:"workouts,omitempty"`
+}
+
+// ProgramWorkout is a WOD scheduled on a day of a program
+type ProgramWorkout struct {
+	Week int `json:"week"`
+	Day  int `json:"day"`
+	WOD  WOD `json:"wod"`
+}
+
+// ProgramAssignmentInput is the data required to assign a program to athletes or groups
+type ProgramAssignmentInput struct {
+	UserIDs   []int `json:"userIDs"`
+	GroupIDs  []int `json:"groupIDs"`
+	StartDate int64 `json:"startDate"`
+}
+
+// AssignedWorkout is a program workout scheduled for an athlete on a given date
+type AssignedWorkout struct {
+	ProgramID   int    `json:"programID"`
+	ProgramName string `json:"programName"`
+	Date        int64  `json:"date"`
+	ProgramWorkout
+	ActivityID *int64 `json:"activityID,omitempty"`
+}
+
+// ProgramProgress is how far an athlete has got through a program
+type ProgramProgress struct {
+	UserID    int     `json:"userID"`
+	Username  string  `json:"username"`
+	Workouts  int     `json:"workouts"`
+	Due       int     `json:"due"`
+	Completed int     `json:"completed"`
+	Rate      float64 `json:"rate"`
+}
+
+// GroupInput is the data required to create a group of athletes
+type GroupInput struct {
+	Name      string `json:"name"`
+	MemberIDs []int  `json:"memberIDs"`
+}
+
+// MetricInput is the data required to record a body metric or benchmark test. Benchmarks need a name (e.g.
+// "2k row") and can be in any unit.
+type MetricInput struct {
+	Type  string  `json:"type"`
+	Name  *string `json:"name,omitempty"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+	Date  int64   `json:"date"`
+	Notes *string `json:"notes,omitempty"`
+}
+
+// Metric is the data object returned for each body metric or benchmark test
+type Metric struct {
+	ID int64 `json:"id"`
+	MetricInput
+}
+
+// MetricTrend summarises how a metric has changed over time
+type MetricTrend struct {
+	Type      string  `json:"type"`
+	Name      *string `json:"name,omitempty"`
+	Unit      string  `json:"unit"`
+	Count     int     `json:"count"`
+	FirstDate int64   `json:"firstDate"`
+	First     float64 `json:"first"`
+	LastDate  int64   `json:"lastDate"`
+	Latest    float64 `json:"latest"`
+	Min       float64 `json:"min"`
+	Max       float64 `json:"max"`
+	Average   float64 `json:"average"`
+	Change    float64 `json:"change"`
+}
+
+// GoalInput is the data required to set a goal. Score goals are a time to beat on a WOD; frequency goals are a
+// number of sessions and volume goals a total time taken (seconds) or MEPs in each period.
+type GoalInput struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	WODID     *int    `json:"wodID,omitempty"`
+	Tier      *string `json:"tier,omitempty"`
+	Target    int64   `json:"target"`
+	Measure   *string `json:"measure,omitempty"`
+	Period    *string `json:"period,omitempty"`
+	StartDate int64   `json:"startDate"`
+	Deadline  *int64  `json:"deadline,omitempty"`
+}
+
+// Goal is the data object returned for each goal, with how far the user has got towards it
+type Goal struct {
+	ID int64 `json:"id"`
+	GoalInput
+	Current    *int64  `json:"current,omitempty"`
+	Progress   float64 `json:"progress"`
+	Status     string  `json:"status"`
+	AchievedAt *int64  `json:"achievedAt,omitempty"`
+}
+
+// GoalStats are the Activities a goal is evaluated against
+type GoalStats struct {
+	Times     []int64
+	Count     int64
+	TimeTaken int64
+	MEPs      int64
+}
+
+// GoalSummary counts a user's goals by status
+type GoalSummary struct {
+	Total           int     `json:"total"`
+	Achieved        int     `json:"achieved"`
+	OnTrack         int     `json:"onTrack"`
+	AtRisk          int     `json:"atRisk"`
+	Missed          int     `json:"missed"`
+	AverageProgress float64 `json:"averageProgress"`
+}
+
+// Group is the data object returned for a group of athletes
+type Group struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	CoachID   int    `json:"coachID"`
+	MemberIDs []int  `json:"memberIDs"`
+}
+
+// LiftInput is the data required to log a lift, standalone or as part of an Activity
+type LiftInput struct {
+	Movement   string        `json:"movement"`
+	Date       int64         `json:"date"`
+	ActivityID *int64        `json:"activityID,omitempty"`
+	Sets       int           `json:"sets"`
+	Reps       int           `json:"reps"`
+	Load       units.Measure `json:"load"`
+	Notes      *string       `json:"notes,omitempty"`
+}
+
+// Lift is the data object returned for each lift
+type Lift struct {
+	ID int64 `json:"id"`
+	LiftInput
+	EstimatedOneRM *units.Measure `json:"estimatedOneRM,omitempty"`
+}
+
+// LiftPR is a user's best lift for a movement
+type LiftPR struct {
+	Movement         string        `json:"movement"`
+	EstimatedOneRM   units.Measure `json:"estimatedOneRM"`
+	LiftID           int64         `json:"liftID"`
+	Date             int64         `json:"date"`
+	Reps             int           `json:"reps"`
+	Load             units.Measure `json:"load"`
+	HeaviestLoad     units.Measure `json:"heaviestLoad"`
+	Bodyweight       *Metric       `json:"bodyweight,omitempty"`
+	RelativeStrength *float64      `json:"relativeStrength,omitempty"`
+}
+
+// LiftPercentage is the load to lift at a percentage of a one rep max
+type LiftPercentage struct {
+	Percent int           `json:"percent"`
+	Load    units.Measure `json:"load"`
+}
+
+// LiftPercentages is the percentages table for a movement
+type LiftPercentages struct {
+	Movement    string           `json:"movement"`
+	Formula     string           `json:"formula"`
+	OneRM       units.Measure    `json:"oneRM"`
+	Percentages []LiftPercentage `json:"percentages"`
+}
+
 // WODFilter is used to model filterable aspects for WODs
 type WODFilter struct {
-	Source    string    `json:"source"`
-	StartDate time.Time `json:"startDate"`
-	EndDate   time.Time `json:"endDate"`
-	Exercise  []string  `json:"exercise"`
-	Picture   *bool     `json:"picture"`
-	Type      string    `json:"type"`
-	Tried     *bool     `json:"tried"`
+	Source     string         `json:"source"`
+	StartDate  time.Time      `json:"startDate"`
+	EndDate    time.Time      `json:"endDate"`
+	Exercise   []string       `json:"exercise"`
+	Picture    *bool          `json:"picture"`
+	Type       string         `json:"type"`
+	Tried      *bool          `json:"tried"`
+	Visibility string         `json:"visibility"`
+	MinLoad    *units.Measure `json:"minLoad"`
+	MaxLoad    *units.Measure `json:"maxLoad"`
 }
 
 // ActivityFilter is used to model filterable aspects for Activities
@@ -86,6 +608,55 @@ type ActivityFilter struct {
 	WODID     string    `json:"wodID"`
 	StartDate time.Time `json:"startDate"`
 	EndDate   time.Time `json:"endDate"`
+	Following *bool     `json:"following"`
+}
+
+// LeaderboardFilter is used to model filterable aspects for leaderboards
+type LeaderboardFilter struct {
+	Tier      string `json:"tier"`
+	Division  string `json:"division"`
+	Following *bool  `json:"following"`
+}
+
+// PlannedSessionFilter is used to model filterable aspects for planned sessions
+type PlannedSessionFilter struct {
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+	Completed *bool     `json:"completed"`
+}
+
+// MetricFilter is used to model filterable aspects for metrics
+type MetricFilter struct {
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+}
+
+// PageFilter is used to page through the feed and comments
+type PageFilter struct {
+	Limit  *int   `json:"limit"`
+	Cursor string `json:"cursor"`
+}
+
+// AuditFilter is used to model filterable aspects for the audit log
+type AuditFilter struct {
+	ActorID      *int      `json:"actorID"`
+	Action       string    `json:"action"`
+	ResourceType string    `json:"resourceType"`
+	ResourceID   string    `json:"resourceID"`
+	StartDate    time.Time `json:"startDate"`
+	EndDate      time.Time `json:"endDate"`
+	PageFilter
+}
+
+// LiftFilter is used to model filterable aspects for lifts
+type LiftFilter struct {
+	Movement   string    `json:"movement"`
+	ActivityID *int64    `json:"activityID"`
+	StartDate  time.Time `json:"startDate"`
+	EndDate    time.Time `json:"endDate"`
+	Formula    string    `json:"formula"`
 }
 
 // WODFilters will get and return any filters applied to the WODs endpoint
@@ -110,6 +681,113 @@ func ActivityFilters(c *gin.Context) (filters *ActivityFilter, err error) {
 	return
 }
 
+// LeaderboardFilters will get and return any filters applied to the leaderboard endpoint
+func LeaderboardFilters(c *gin.Context) (filters *LeaderboardFilter, err error) {
+	filters = &LeaderboardFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	if filters.Tier != "" && !ValidTier(filters.Tier) {
+		err = fmt.Errorf("Invalid value for parameter 'tier': '%s'", filters.Tier)
+	} else if filters.Division != "" && !ValidDivision(filters.Division) {
+		err = fmt.Errorf("Invalid value for parameter 'division': '%s'", filters.Division)
+	}
+
+	return
+}
+
+// PlannedSessionFilters will get and return any filters applied to the planned sessions endpoint
+func PlannedSessionFilters(c *gin.Context) (filters *PlannedSessionFilter, err error) {
+	filters = &PlannedSessionFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	return
+}
+
+// MetricFilters will get and return any filters applied to the metrics endpoints
+func MetricFilters(c *gin.Context) (filters *MetricFilter, err error) {
+	filters = &MetricFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	return
+}
+
+// PageFilters will get and return the page asked for, checking the limit is between 1 and MaxPageSize and the
+// cursor is one handed out with an earlier page
+func PageFilters(c *gin.Context) (filters *PageFilter, err error) {
+	filters = &PageFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	err = filters.check()
+
+	return
+}
+
+// AuditFilters will get and return any filters applied to the audit log endpoint
+func AuditFilters(c *gin.Context) (filters *AuditFilter, err error) {
+	filters = &AuditFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	if filters.ResourceType != "" && !ValidResourceType(filters.ResourceType) {
+		err = fmt.Errorf("Invalid value for parameter 'resourceType': '%s'", filters.ResourceType)
+		return
+	}
+
+	err = filters.PageFilter.check()
+
+	return
+}
+
+// check defaults the limit, checking it's between 1 and MaxPageSize and the cursor is one handed out with an
+// earlier page
+func (filters *PageFilter) check() error {
+	if filters.Limit == nil {
+		limit := DefaultPageSize
+		filters.Limit = &limit
+	} else if *filters.Limit < 1 || *filters.Limit > MaxPageSize {
+		return fmt.Errorf("Invalid value for parameter 'limit': '%d' (must be 1 to %d)", *filters.Limit, MaxPageSize)
+	}
+
+	if filters.Cursor != "" {
+		if _, _, err := DecodeCursor(filters.Cursor); err != nil {
+			return fmt.Errorf("Invalid value for parameter 'cursor': '%s'", filters.Cursor)
+		}
+	}
+
+	return nil
+}
+
+// LiftFilters will get and return any filters applied to the lift endpoints
+func LiftFilters(c *gin.Context) (filters *LiftFilter, err error) {
+	filters = &LiftFilter{}
+
+	if err = GetFilters(c, filters); err != nil {
+		return
+	}
+
+	if filters.Formula == "" {
+		filters.Formula = FormulaEpley
+	} else if !ValidFormula(filters.Formula) {
+		err = fmt.Errorf("Invalid value for parameter 'formula': '%s'", filters.Formula)
+	}
+
+	return
+}
+
 // GetFilters extracts filter parameters from the context
 func GetFilters(c *gin.Context, filter interface{}) error {
 
@@ -224,6 +902,14 @@ func GetFilters(c *gin.Context, filter interface{}) error {
 						return fmt.Errorf("Invalid value for integer parameter '%s': '%s'", paramName, param)
 					}
 					f.Set(reflect.ValueOf(&v))
+				case reflect.Struct:
+					if f.Type().Elem() == reflect.TypeOf(units.Measure{}) {
+						v, err := units.Parse(param)
+						if err != nil {
+							return fmt.Errorf("Invalid value for measure parameter '%s': '%s'", paramName, param)
+						}
+						f.Set(reflect.ValueOf(&v))
+					}
 				}
 			}
 		case reflect.Struct:
@@ -260,6 +946,18 @@ func GetFilters(c *gin.Context, filter interface{}) error {
 	return nil
 }
 
+// StartOfDay returns the unix time of midnight on the day of t in loc (the user's time zone)
+func StartOfDay(t int64, loc *time.Location) int64 {
+	year, month, day := time.Unix(t, 0).In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc).Unix()
+}
+
+// NextDay returns the unix time of midnight on the day after day (a StartOfDay) in loc, which isn't always a
+// whole day later when the clocks change
+func NextDay(day int64, loc *time.Location) int64 {
+	return time.Unix(day, 0).In(loc).AddDate(0, 0, 1).Unix()
+}
+
 // ParseDateTimeString parses a date string in RFC3339 format
 // (a more constrained subset of ISO8601) and returns the time.Time representation of it
 func ParseDateTimeString(date string) (time.Time, error) {
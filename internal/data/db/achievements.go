@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// AwardAchievements will evaluate rules over the user's whole history, store any badges newly earned and return
+// every badge with the user's progress. Badges stay earned even if the Activities that earned them are removed.
+func AwardAchievements(db *sql.DB, rules []data.AchievementRule, userID int) ([]data.Achievement, error) {
+	events, err := getAchievementEvents(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	achievements := data.EvaluateAchievements(rules, events, loc)
+
+	insertQuery := psql.
+		Insert("user_achievement").
+		Columns("user_id, achievement_id, earned_at").
+		Suffix("ON CONFLICT (user_id, achievement_id) DO NOTHING")
+	earned := 0
+	for _, achievement := range achievements {
+		if achievement.Earned {
+			insertQuery = insertQuery.Values(userID, achievement.ID, *achievement.EarnedAt)
+			earned++
+		}
+	}
+
+	if earned > 0 {
+		sqlInsertQuery, args, _ := insertQuery.ToSql()
+		if _, err := db.Exec(sqlInsertQuery, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	stored, err := getEarnedAchievements(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range achievements {
+		if earnedAt, ok := stored[achievements[i].ID]; ok {
+			achievements[i].Earned = true
+			achievements[i].EarnedAt = &earnedAt
+			achievements[i].Progress = achievements[i].Target
+		}
+	}
+
+	return achievements, nil
+}
+
+// getAchievementEvents reads the user's Activities, oldest first
+func getAchievementEvents(db queryer, userID int) ([]data.AchievementEvent, error) {
+	var events []data.AchievementEvent
+
+	selectQuery := psql.
+		Select("activity.date, activity.wod_id, wod.type, activity.time_taken").
+		From("activity").
+		Join("wod ON wod.id = activity.wod_id").
+		Where(sq.Eq{"activity.user_id": userID}).
+		OrderBy("activity.date, activity.id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var event data.AchievementEvent
+
+		if err := rows.Scan(&event.Date, &event.WODID, &event.WODType, &event.TimeTaken); err != nil {
+			return nil, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// getEarnedAchievements reads when the user earned each of their badges
+func getEarnedAchievements(db queryer, userID int) (map[string]int64, error) {
+	earned := map[string]int64{}
+
+	selectQuery := psql.
+		Select("achievement_id, earned_at").
+		From("user_achievement").
+		Where(sq.Eq{"user_id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var achievementID string
+		var earnedAt int64
+
+		if err := rows.Scan(&achievementID, &earnedAt); err != nil {
+			return nil, err
+		}
+
+		earned[achievementID] = earnedAt
+	}
+
+	return earned, rows.Err()
+}
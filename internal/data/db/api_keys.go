@@ -0,0 +1,130 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// ErrTooManyAPIKeys is returned when the user already has data.MaxAPIKeys API keys
+var ErrTooManyAPIKeys = errors.New("too many API keys")
+
+// apiKeyPrefixLength is how much of a key is kept to tell keys apart in lists
+const apiKeyPrefixLength = 8
+
+// CreateAPIKey will create an API key for the user, returning it with the key (only its hash is stored)
+func CreateAPIKey(db *sql.DB, input data.APIKeyInput, userID int) (data.APIKey, error) {
+	apiKey := data.APIKey{Name: input.Name, Scopes: input.Scopes, ExpiresAt: input.ExpiresAt, CreatedAt: time.Now().Unix()}
+
+	countQuery := psql.
+		Select("COUNT(*)").
+		From("api_key").
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil})
+	sqlCountQuery, countArgs, _ := countQuery.ToSql()
+
+	var count int
+	if err := db.QueryRow(sqlCountQuery, countArgs...).Scan(&count); err != nil {
+		return apiKey, err
+	} else if count >= data.MaxAPIKeys {
+		return apiKey, ErrTooManyAPIKeys
+	}
+
+	token, hash, err := data.NewToken()
+	if err != nil {
+		return apiKey, err
+	}
+	apiKey.Key = data.APIKeyPrefix + token
+	apiKey.Prefix = apiKey.Key[:len(data.APIKeyPrefix)+apiKeyPrefixLength]
+
+	insertQuery := psql.
+		Insert("api_key").
+		Columns("user_id, name, token_hash, prefix, scopes, created_at, expires_at").
+		Values(userID, apiKey.Name, hash, apiKey.Prefix, pq.StringArray(apiKey.Scopes), apiKey.CreatedAt, apiKey.ExpiresAt).
+		Suffix("RETURNING \"id\"")
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	err = db.QueryRow(sqlQuery, args...).Scan(&apiKey.ID)
+	return apiKey, err
+}
+
+// GetAPIKeys will get and return the user's unrevoked API keys (without the keys themselves)
+func GetAPIKeys(db *sql.DB, userID int) ([]data.APIKey, error) {
+	var dbAPIKeys = []data.APIKey{}
+
+	selectQuery := psql.
+		Select("id, name, prefix, scopes, created_at, last_used_at, expires_at").
+		From("api_key").
+		Where(sq.Eq{"user_id": userID, "revoked_at": nil}).
+		OrderBy("created_at DESC")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var apiKey data.APIKey
+		var scopes pq.StringArray
+
+		if err := rows.Scan(&apiKey.ID, &apiKey.Name, &apiKey.Prefix, &scopes, &apiKey.CreatedAt, &apiKey.LastUsedAt, &apiKey.ExpiresAt); err != nil {
+			return nil, err
+		}
+
+		apiKey.Scopes = scopes
+		dbAPIKeys = append(dbAPIKeys, apiKey)
+	}
+
+	return dbAPIKeys, nil
+}
+
+// RevokeAPIKey will revoke one of the user's API keys
+func RevokeAPIKey(db *sql.DB, keyID string, userID int) error {
+	updateQuery := psql.
+		Update("api_key").
+		Set("revoked_at", time.Now().Unix()).
+		Where(sq.Eq{"id": keyID, "user_id": userID, "revoked_at": nil})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	revoked, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if revoked == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetAPIKeyUser will return the user an unrevoked, unexpired API key belongs to and the key's scopes (noting
+// that the key has been used)
+func GetAPIKeyUser(db *sql.DB, key string) (data.User, []string, error) {
+	var user data.User
+	var scopes pq.StringArray
+
+	now := time.Now().Unix()
+
+	updateQuery := psql.
+		Update("api_key").
+		Set("last_used_at", now).
+		Where(sq.Eq{"token_hash": data.HashToken(key), "revoked_at": nil}).
+		Where(sq.Or{sq.Eq{"expires_at": nil}, sq.Gt{"expires_at": now}}).
+		Suffix("RETURNING user_id, " +
+			"(SELECT username FROM \"user\" WHERE \"user\".id = api_key.user_id), " +
+			"(SELECT role FROM \"user\" WHERE \"user\".id = api_key.user_id), scopes")
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	err := db.QueryRow(sqlQuery, args...).Scan(&user.ID, &user.Username, &user.Role, &scopes)
+	return user, scopes, err
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// AppendAudit will add an entry to the audit log. The audit log is only ever added to: nothing here changes or
+// deletes entries (not even deleting an account), and the table should be set up so the service can't either.
+func AppendAudit(db queryer, entry data.AuditEntry) error {
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+
+	insertQuery := psql.
+		Insert("audit_log").
+		Columns("actor_id, actor, action, resource_type, resource_id, before, after, ip, request_id, created_at").
+		Values(entry.ActorID, entry.Actor, entry.Action, entry.ResourceType, entry.ResourceID, jsonOrNull(entry.Before),
+			jsonOrNull(entry.After), entry.IP, entry.RequestID, entry.CreatedAt)
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return err
+}
+
+// GetAuditLog will get and return a page of the audit log, newest first (only by the actor, for the resource and
+// between the dates asked for)
+func GetAuditLog(db *sql.DB, filters *data.AuditFilter) (data.AuditPage, error) {
+	page := data.AuditPage{Items: []data.AuditEntry{}}
+
+	selectQuery := psql.
+		Select("id, actor_id, actor, action, resource_type, resource_id, before, after, ip, request_id, created_at").
+		From("audit_log").
+		OrderBy("created_at DESC, id DESC").
+		Limit(uint64(*filters.Limit + 1))
+
+	if filters.ActorID != nil {
+		selectQuery = selectQuery.Where(sq.Eq{"actor_id": *filters.ActorID})
+	}
+	if filters.Action != "" {
+		selectQuery = selectQuery.Where(sq.Eq{"action": filters.Action})
+	}
+	if filters.ResourceType != "" {
+		selectQuery = selectQuery.Where(sq.Eq{"resource_type": filters.ResourceType})
+	}
+	if filters.ResourceID != "" {
+		selectQuery = selectQuery.Where(sq.Eq{"resource_id": filters.ResourceID})
+	}
+	if !filters.StartDate.IsZero() {
+		selectQuery = selectQuery.Where(sq.GtOrEq{"created_at": filters.StartDate.Unix()})
+	}
+	if !filters.EndDate.IsZero() {
+		selectQuery = selectQuery.Where(sq.LtOrEq{"created_at": filters.EndDate.Unix()})
+	}
+
+	if filters.Cursor != "" {
+		createdAt, id, err := data.DecodeCursor(filters.Cursor)
+		if err != nil {
+			return page, err
+		}
+		selectQuery = selectQuery.Where("(created_at, id) < (?, ?)", createdAt, id)
+	}
+
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return page, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var entry data.AuditEntry
+		var before, after []byte
+
+		if err := rows.Scan(&entry.ID, &entry.ActorID, &entry.Actor, &entry.Action, &entry.ResourceType, &entry.ResourceID,
+			&before, &after, &entry.IP, &entry.RequestID, &entry.CreatedAt); err != nil {
+			return page, err
+		}
+
+		entry.Before, entry.After = before, after
+		page.Items = append(page.Items, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > *filters.Limit {
+		page.Items = page.Items[:*filters.Limit]
+		last := page.Items[len(page.Items)-1]
+		next := data.EncodeCursor(last.CreatedAt, last.ID)
+		page.Next = &next
+	}
+
+	return page, nil
+}
+
+// jsonOrNull stores an empty snapshot as NULL
+func jsonOrNull(snapshot []byte) interface{} {
+	if len(snapshot) == 0 {
+		return nil
+	}
+	return string(snapshot)
+}
@@ -0,0 +1,69 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+)
+
+// CreateCalendarToken will revoke any existing calendar token for the user and store a new one
+func CreateCalendarToken(db *sql.DB, tokenHash string, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeCalendarTokens(tx, userID); err != nil {
+		return err
+	}
+
+	insertQuery := psql.
+		Insert("calendar_token").
+		Columns("user_id, token_hash, created_at").
+		Values(userID, tokenHash, sq.Expr("NOW()"))
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	if _, err := tx.Exec(sqlInsertQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeCalendarTokens will stop all of a user's calendar tokens from working
+func RevokeCalendarTokens(db *sql.DB, userID int) error {
+	return revokeCalendarTokens(db, userID)
+}
+
+func revokeCalendarTokens(db queryer, userID int) error {
+	updateQuery := psql.
+		Update("calendar_token").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"revoked_at": nil})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// GetCalendarTokenUser will return the ID of the user a calendar token belongs to
+func GetCalendarTokenUser(db *sql.DB, tokenHash string) (int, error) {
+	var userID int
+
+	selectQuery := psql.
+		Select("user_id").
+		From("calendar_token").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Eq{"revoked_at": nil})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	err := db.QueryRow(sqlQuery, args...).Scan(&userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
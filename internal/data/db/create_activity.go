@@ -7,18 +7,34 @@ import (
 	"github.com/philLITERALLY/wodland-service/internal/data"
 )
 
-// CreateActivity will create an Activity
-func CreateActivity(db *sql.DB, activity data.ActivityInput, userID int) error {
+// CreateActivity will create an Activity for a WOD the user can see and return its ID
+func CreateActivity(db *sql.DB, activity data.ActivityInput, userID int) (int64, error) {
+	if err := checkWODVisible(db, *activity.WODID, userID); err != nil {
+		return 0, err
+	}
+
+	return insertActivity(db, activity, userID)
+}
+
+// insertActivity adds an Activity using db (or a transaction), completing any session planned for it, and
+// returns its ID. Its tier is left empty if it isn't given, so it isn't mixed in with any tier's results.
+func insertActivity(db queryer, activity data.ActivityInput, userID int) (int64, error) {
 	activityQuery := psql.
 		Insert("activity").
-		Columns("user_id, wod_id, date, time_taken, meps, exertion, notes").
-		Values(userID, activity.WODID, activity.Date, activity.TimeTaken, activity.MEPs, activity.Exertion, activity.Notes)
+		Columns("user_id, wod_id, date, time_taken, meps, exertion, notes, tier, substitutions").
+		Values(userID, activity.WODID, activity.Date, activity.TimeTaken, activity.MEPs, activity.Exertion, activity.Notes, activity.Tier, activity.Substitutions).
+		Suffix("RETURNING \"id\"")
 	sqlActivityQuery, args, _ := activityQuery.ToSql()
 
-	_, err := db.Exec(sqlActivityQuery, args...)
+	var activityID int64
+	err := db.QueryRow(sqlActivityQuery, args...).Scan(&activityID)
 	if err != nil {
-		return err
+		return 0, err
+	}
+
+	if err := linkPlannedSession(db, activityID, activity, userID); err != nil {
+		return 0, err
 	}
 
-	return nil
+	return activityID, nil
 }
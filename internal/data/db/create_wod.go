@@ -5,32 +5,92 @@ import (
 
 	_ "github.com/heroku/x/hmetrics/onload"
 	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/units"
 )
 
-// CreateWOD will create a WOD (and add an attempt if supplied)
-func CreateWOD(db *sql.DB, WOD data.CreateWOD, userID int) error {
+// CreateWOD will create a WOD (and add an attempt if supplied), returning their IDs (the attempt's is 0 if there
+// wasn't one). The WOD, its scaling options and the attempt are all added or none are.
+func CreateWOD(db *sql.DB, WOD data.CreateWOD, userID int) (int, int64, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	wodID, err := insertWOD(tx, WOD.WODInput, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// The user created the WOD so can always see it, there's no need to check like CreateActivity does
+	var activityID int64
+	if WOD.ActivityInput != nil {
+		activity := WOD.ActivityInput
+		activity.WODID = &wodID
+
+		activityID, err = insertActivity(tx, *activity, userID)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return wodID, activityID, tx.Commit()
+}
+
+// insertWOD adds a WOD using db (or a transaction) and returns its ID. WODs are only seen by their creator (or
+// their gym) unless they're made public.
+func insertWOD(db queryer, WOD data.WODInput, userID int) (int, error) {
+	if WOD.Visibility == "" {
+		WOD.Visibility = data.VisibilityPrivate
+		if WOD.GymID != nil {
+			WOD.Visibility = data.VisibilityGym
+		}
+	}
+
 	wodQuery := psql.
 		Insert("wod").
-		Columns("source, creation_t, wod, picture, type, created_by").
-		Values(WOD.Source, WOD.CreationT, WOD.Exercise, WOD.Picture, WOD.Type, userID).
+		Columns("source, creation_t, wod, picture, type, gym_id, visibility, created_by").
+		Values(WOD.Source, WOD.CreationT, WOD.Exercise, WOD.Picture, WOD.Type, WOD.GymID, WOD.Visibility, userID).
 		Suffix("RETURNING \"id\"")
 	sqlWODQuery, wodArgs, _ := wodQuery.ToSql()
 
 	var wodID int
-	wodErr := db.QueryRow(sqlWODQuery, wodArgs...).Scan(&wodID)
-	if wodErr != nil {
-		return wodErr
+	err := db.QueryRow(sqlWODQuery, wodArgs...).Scan(&wodID)
+	if err != nil {
+		return 0, err
 	}
 
-	if WOD.ActivityInput != nil {
-		activity := WOD.ActivityInput
-		activity.WODID = &wodID
+	if len(WOD.Scaling) > 0 {
+		scalingQuery := psql.
+			Insert("wod_scaling").
+			Columns("wod_id, tier, load, distance, calories, loads, description")
+		for _, option := range WOD.Scaling {
+			load, distance, calories := scalingMeasures(option)
+			scalingQuery = scalingQuery.Values(wodID, option.Tier, load, distance, calories, option.Loads, option.Description)
+		}
+		sqlScalingQuery, scalingArgs, _ := scalingQuery.ToSql()
+
+		if _, err := db.Exec(sqlScalingQuery, scalingArgs...); err != nil {
+			return 0, err
+		}
+	}
+
+	return wodID, nil
+}
+
+// scalingMeasures splits a scaling option's load (kg) and distance (metres or calories) into their columns
+func scalingMeasures(option data.ScalingOption) (load *float64, distance *float64, calories *float64) {
+	if option.Load != nil {
+		load = &option.Load.Amount
+	}
 
-		activityErr := CreateActivity(db, *activity, userID)
-		if activityErr != nil {
-			return activityErr
+	if option.Distance != nil {
+		if option.Distance.Unit == units.Calories {
+			calories = &option.Distance.Amount
+		} else {
+			distance = &option.Distance.Amount
 		}
 	}
 
-	return nil
+	return
 }
@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// ExportActivities will pass every Activity matching the filters to fn, one row at a time
+func ExportActivities(db *sql.DB, filters *data.ActivityFilter, userID int, fn func(data.Activity) error) error {
+	selectQuery := selectActivities(filters, userID).OrderBy("date, activity.id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(activity); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
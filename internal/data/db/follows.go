@@ -0,0 +1,289 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// FollowUser will follow another user, returning whether the follow is accepted or waiting for them to accept
+// it (as it does for private profiles). Following someone already followed leaves the follow as it is.
+func FollowUser(db *sql.DB, followeeID int, userID int) (string, error) {
+	selectQuery := psql.
+		Select("COALESCE(private, false)").
+		From("\"user\"").
+		Where(sq.Eq{"id": followeeID}).
+		Where(notBlocked("id", userID))
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var private bool
+	if err := db.QueryRow(sqlQuery, args...).Scan(&private); err != nil {
+		return "", err
+	}
+
+	status := data.FollowAccepted
+	if private {
+		status = data.FollowPending
+	}
+
+	insertQuery := psql.
+		Insert("follow").
+		Columns("follower_id, followee_id, status, created_at").
+		Values(userID, followeeID, status, time.Now().Unix()).
+		Suffix("ON CONFLICT (follower_id, followee_id) DO UPDATE SET status = follow.status RETURNING status")
+	sqlInsertQuery, insertArgs, _ := insertQuery.ToSql()
+
+	err := db.QueryRow(sqlInsertQuery, insertArgs...).Scan(&status)
+	return status, err
+}
+
+// UnfollowUser will stop following another user (or take back a request to)
+func UnfollowUser(db *sql.DB, followeeID int, userID int) error {
+	return deleteFollow(db, userID, followeeID)
+}
+
+// RemoveFollower will stop another user following the user (or turn down their request to)
+func RemoveFollower(db *sql.DB, followerID int, userID int) error {
+	return deleteFollow(db, followerID, userID)
+}
+
+// AcceptFollower will accept another user's request to follow the user
+func AcceptFollower(db *sql.DB, followerID int, userID int) error {
+	updateQuery := psql.
+		Update("follow").
+		Set("status", data.FollowAccepted).
+		Where(sq.Eq{"follower_id": followerID, "followee_id": userID, "status": data.FollowPending})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetFollowing will get and return who the user follows (including requests waiting to be accepted)
+func GetFollowing(db *sql.DB, userID int) ([]data.Connection, error) {
+	selectQuery := psql.
+		Select("\"user\".id, \"user\".username, follow.status, follow.created_at").
+		From("follow").
+		Join("\"user\" ON \"user\".id = follow.followee_id").
+		Where(sq.Eq{"follow.follower_id": userID}).
+		OrderBy("\"user\".username")
+
+	return getConnections(db, selectQuery)
+}
+
+// GetFollowers will get and return who follows the user, or who is asking to if status is pending
+func GetFollowers(db *sql.DB, status string, userID int) ([]data.Connection, error) {
+	selectQuery := psql.
+		Select("\"user\".id, \"user\".username, follow.status, follow.created_at").
+		From("follow").
+		Join("\"user\" ON \"user\".id = follow.follower_id").
+		Where(sq.Eq{"follow.followee_id": userID, "follow.status": status}).
+		OrderBy("\"user\".username")
+
+	return getConnections(db, selectQuery)
+}
+
+// BlockUser will block another user, ending any follows between them
+func BlockUser(db *sql.DB, blockedID int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	selectQuery := psql.
+		Select("id").
+		From("\"user\"").
+		Where(sq.Eq{"id": blockedID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var id int
+	if err := tx.QueryRow(sqlQuery, args...).Scan(&id); err != nil {
+		return err
+	}
+
+	insertQuery := psql.
+		Insert("block").
+		Columns("blocker_id, blocked_id, created_at").
+		Values(userID, blockedID, time.Now().Unix()).
+		Suffix("ON CONFLICT DO NOTHING")
+	sqlInsertQuery, insertArgs, _ := insertQuery.ToSql()
+
+	if _, err := tx.Exec(sqlInsertQuery, insertArgs...); err != nil {
+		return err
+	}
+
+	deleteQuery := psql.
+		Delete("follow").
+		Where(sq.Or{
+			sq.Eq{"follower_id": userID, "followee_id": blockedID},
+			sq.Eq{"follower_id": blockedID, "followee_id": userID},
+		})
+	sqlDeleteQuery, deleteArgs, _ := deleteQuery.ToSql()
+
+	if _, err := tx.Exec(sqlDeleteQuery, deleteArgs...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// UnblockUser will unblock another user
+func UnblockUser(db *sql.DB, blockedID int, userID int) error {
+	deleteQuery := psql.
+		Delete("block").
+		Where(sq.Eq{"blocker_id": userID, "blocked_id": blockedID})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetBlocked will get and return who the user has blocked
+func GetBlocked(db *sql.DB, userID int) ([]data.Connection, error) {
+	selectQuery := psql.
+		Select("\"user\".id, \"user\".username, '', block.created_at").
+		From("block").
+		Join("\"user\" ON \"user\".id = block.blocked_id").
+		Where(sq.Eq{"block.blocker_id": userID}).
+		OrderBy("\"user\".username")
+
+	return getConnections(db, selectQuery)
+}
+
+// GetPrivacy will return whether the user has to accept people before they can follow them
+func GetPrivacy(db *sql.DB, userID int) (data.Privacy, error) {
+	selectQuery := psql.
+		Select("COALESCE(private, false)").
+		From("\"user\"").
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var privacy data.Privacy
+	err := db.QueryRow(sqlQuery, args...).Scan(&privacy.Private)
+	return privacy, err
+}
+
+// UpdatePrivacy will change whether the user has to accept followers (making their profile public accepts
+// everyone waiting)
+func UpdatePrivacy(db *sql.DB, privacy data.Privacy, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("private", privacy.Private).
+		Where(sq.Eq{"id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	if _, err := tx.Exec(sqlUpdateQuery, args...); err != nil {
+		return err
+	}
+
+	if !privacy.Private {
+		acceptQuery := psql.
+			Update("follow").
+			Set("status", data.FollowAccepted).
+			Where(sq.Eq{"followee_id": userID, "status": data.FollowPending})
+		sqlAcceptQuery, acceptArgs, _ := acceptQuery.ToSql()
+
+		if _, err := tx.Exec(sqlAcceptQuery, acceptArgs...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// followedBy matches rows where column is someone the user follows (and has been accepted by)
+func followedBy(column string, userID int) sq.Sqlizer {
+	return sq.Select("followee_id").
+		From("follow").
+		Where(sq.Eq{"follower_id": userID, "status": data.FollowAccepted}).
+		Prefix(column + " IN (").
+		Suffix(")")
+}
+
+// notBlocked matches rows where column is nobody the user has blocked or been blocked by
+func notBlocked(column string, userID int) sq.Sqlizer {
+	return sq.Select("blocked_id").
+		From("block").
+		Where(sq.Eq{"blocker_id": userID}).
+		Prefix(column+" NOT IN (").
+		Suffix("UNION SELECT blocker_id FROM block WHERE blocked_id = ?)", userID)
+}
+
+// deleteFollow removes the follower's follow of (or request to follow) the followee
+func deleteFollow(db *sql.DB, followerID int, followeeID int) error {
+	deleteQuery := psql.
+		Delete("follow").
+		Where(sq.Eq{"follower_id": followerID, "followee_id": followeeID})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// getConnections reads the users selected by selectQuery (id, username, status, since)
+func getConnections(db *sql.DB, selectQuery sq.SelectBuilder) ([]data.Connection, error) {
+	var dbConnections = []data.Connection{}
+
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var connection data.Connection
+
+		if err := rows.Scan(&connection.UserID, &connection.Username, &connection.Status, &connection.Since); err != nil {
+			return nil, err
+		}
+
+		dbConnections = append(dbConnections, connection)
+	}
+
+	return dbConnections, nil
+}
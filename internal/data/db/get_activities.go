@@ -12,14 +12,7 @@ import (
 func GetActivities(db *sql.DB, filters *data.ActivityFilter, userID int) ([]data.Activity, error) {
 	var dbActivities = []data.Activity{}
 
-	selectQuery := psql.
-		Select("activity.id, date, time_taken, meps, exertion, notes, wod.*").
-		From("activity").
-		Join("wod ON wod.id = activity.wod_id").
-		Where(sq.Eq{"user_id": userID})
-
-	selectQuery = processActivityFilters(selectQuery, filters)
-	selectQuery = selectQuery.Limit(10)
+	selectQuery := selectActivities(filters, userID).Limit(10)
 	sqlQuery, args, _ := selectQuery.ToSql()
 
 	rows, err := db.Query(sqlQuery, args...)
@@ -29,20 +22,52 @@ func GetActivities(db *sql.DB, filters *data.ActivityFilter, userID int) ([]data
 
 	defer rows.Close()
 	for rows.Next() {
-		var activity data.Activity
-		var wod data.WOD
-
-		if err := rows.Scan(&activity.ID, &activity.Date, &activity.TimeTaken, &activity.MEPs, &activity.Exertion, &activity.Notes, &wod.ID, &wod.Source, &wod.CreationT, &wod.Exercise, &wod.Picture, &wod.Type); err != nil {
+		activity, err := scanActivity(rows)
+		if err != nil {
 			return nil, err
 		}
 
-		activity.WOD = &wod
 		dbActivities = append(dbActivities, activity)
 	}
 
 	return dbActivities, nil
 }
 
+// selectActivities builds the query for a user's Activities, or those of people they follow (joined with their WOD)
+func selectActivities(filters *data.ActivityFilter, userID int) sq.SelectBuilder {
+	selectQuery := psql.
+		Select("activity.id, activity.user_id, \"user\".username, date, time_taken, meps, exertion, notes, tier, substitutions, " + wodColumns).
+		From("activity").
+		Join("wod ON wod.id = activity.wod_id").
+		Join("\"user\" ON \"user\".id = activity.user_id")
+
+	if filters.Following != nil && *filters.Following {
+		selectQuery = selectQuery.
+			Where(followedBy("activity.user_id", userID)).
+			Where(notBlocked("activity.user_id", userID)).
+			Where(visibleWODs(userID))
+	} else {
+		selectQuery = selectQuery.Where(sq.Eq{"activity.user_id": userID})
+	}
+
+	return processActivityFilters(selectQuery, filters)
+}
+
+// scanActivity reads a row produced by selectActivities
+func scanActivity(rows *sql.Rows) (data.Activity, error) {
+	var activity data.Activity
+	var wod data.WOD
+
+	fields := []interface{}{&activity.ID, &activity.UserID, &activity.Username, &activity.Date, &activity.TimeTaken, &activity.MEPs, &activity.Exertion, &activity.Notes, &activity.Tier, &activity.Substitutions}
+	if err := rows.Scan(append(fields, wodFields(&wod)...)...); err != nil {
+		return activity, err
+	}
+
+	activity.WOD = &wod
+
+	return activity, nil
+}
+
 func processActivityFilters(baseQuery sq.SelectBuilder, filters *data.ActivityFilter) sq.SelectBuilder {
 	baseQuery = processWODIDFilter(baseQuery, filters)
 	baseQuery = processActivityDateFilter(baseQuery, filters)
@@ -12,28 +12,47 @@ import (
 
 var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
+// wodColumns are the columns of a WOD read by wodFields
+const wodColumns = "wod.id, wod.source, wod.creation_t, wod.wod, wod.picture, wod.type, wod.gym_id, wod.visibility, wod.thumbnail"
+
+// wodFields returns the destinations to scan wodColumns into
+func wodFields(wod *data.WOD) []interface{} {
+	return []interface{}{&wod.ID, &wod.Source, &wod.CreationT, &wod.Exercise, &wod.Picture, &wod.Type, &wod.GymID, &wod.Visibility, &wod.Thumbnail}
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // GetWOD will get and return an individual WOD
 func GetWOD(db *sql.DB, wodID string, userID int) (data.WOD, error) {
 	var dbWOD = data.WOD{}
 	var dbActivities []data.Activity
 
 	wodQuery := psql.
-		Select("wod.*, COUNT(activity.id), MIN(activity.time_taken)").
+		Select(wodColumns+", COUNT(activity.id), MIN(activity.time_taken)").
 		From("wod").
-		LeftJoin("activity ON activity.wod_id = wod.id").
+		LeftJoin("activity ON activity.wod_id = wod.id AND activity.user_id = ?", userID).
 		Where(sq.Eq{"wod.id": wodID}).
-		Where(sq.Eq{"activity.user_id": userID}).
+		Where(visibleWODs(userID)).
 		GroupBy("wod.id")
 	sqlWODQuery, args, _ := wodQuery.ToSql()
 
 	err := db.QueryRow(sqlWODQuery, args...).
-		Scan(&dbWOD.ID, &dbWOD.Source, &dbWOD.CreationT, &dbWOD.Exercise, &dbWOD.Picture, &dbWOD.Type, &dbWOD.Attempts, &dbWOD.BestTime)
+		Scan(append(wodFields(&dbWOD), &dbWOD.Attempts, &dbWOD.BestTime)...)
 	if err != nil {
 		return dbWOD, err
 	}
 
+	if err := addScaling(db, []*data.WOD{&dbWOD}, userID); err != nil {
+		return dbWOD, err
+	}
+
 	activityQuery := psql.
-		Select("id, date, time_taken, meps, exertion, notes").
+		Select("id, date, time_taken, meps, exertion, notes, tier, substitutions").
 		From("activity").
 		Where(sq.Eq{"wod_id": wodID}).
 		Where(sq.Eq{"activity.user_id": userID})
@@ -48,7 +67,7 @@ func GetWOD(db *sql.DB, wodID string, userID int) (data.WOD, error) {
 	for rows.Next() {
 		var activity data.Activity
 
-		if err := rows.Scan(&activity.ID, &activity.Date, &activity.TimeTaken, &activity.MEPs, &activity.Exertion, &activity.Notes); err != nil {
+		if err := rows.Scan(&activity.ID, &activity.Date, &activity.TimeTaken, &activity.MEPs, &activity.Exertion, &activity.Notes, &activity.Tier, &activity.Substitutions); err != nil {
 			return dbWOD, err
 		}
 
@@ -67,13 +86,12 @@ func GetWODs(db *sql.DB, filters *data.WODFilter, userID int) ([]data.WOD, error
 	var dbWODs = []data.WOD{}
 
 	selectQuery := psql.
-		Select("wod.*, COUNT(activity.id), MIN(activity.time_taken)").
+		Select(wodColumns+", COUNT(activity.id), MIN(activity.time_taken)").
 		From("wod").
-		LeftJoin("activity ON activity.wod_id = wod.id").
-		Where(sq.Eq{"activity.user_id": userID}).
+		LeftJoin("activity ON activity.wod_id = wod.id AND activity.user_id = ?", userID).
 		GroupBy("wod.id")
 
-	selectQuery = processWODFilters(selectQuery, filters)
+	selectQuery = processWODFilters(selectQuery, filters, userID)
 	selectQuery = selectQuery.Limit(10)
 	sqlQuery, args, _ := selectQuery.ToSql()
 
@@ -86,23 +104,34 @@ func GetWODs(db *sql.DB, filters *data.WODFilter, userID int) ([]data.WOD, error
 	for rows.Next() {
 		var wod data.WOD
 
-		if err := rows.Scan(&wod.ID, &wod.Source, &wod.CreationT, &wod.Exercise, &wod.Picture, &wod.Type, &wod.Attempts, &wod.BestTime); err != nil {
+		if err := rows.Scan(append(wodFields(&wod), &wod.Attempts, &wod.BestTime)...); err != nil {
 			return nil, err
 		}
 
 		dbWODs = append(dbWODs, wod)
 	}
 
+	wods := make([]*data.WOD, len(dbWODs))
+	for i := range dbWODs {
+		wods[i] = &dbWODs[i]
+	}
+
+	if err := addScaling(db, wods, userID); err != nil {
+		return nil, err
+	}
+
 	return dbWODs, nil
 }
 
-func processWODFilters(baseQuery sq.SelectBuilder, filters *data.WODFilter) sq.SelectBuilder {
+func processWODFilters(baseQuery sq.SelectBuilder, filters *data.WODFilter, userID int) sq.SelectBuilder {
+	baseQuery = processVisibilityFilter(baseQuery, filters, userID)
 	baseQuery = processSourceFilter(baseQuery, filters)
 	baseQuery = processWODDateFilter(baseQuery, filters)
 	baseQuery = processExerciseFilter(baseQuery, filters)
 	baseQuery = processPictureFilter(baseQuery, filters)
 	baseQuery = processTypeFilter(baseQuery, filters)
 	baseQuery = processTriedFilter(baseQuery, filters)
+	baseQuery = processLoadFilter(baseQuery, filters)
 
 	return baseQuery
 }
@@ -175,3 +204,76 @@ func processTriedFilter(baseQuery sq.SelectBuilder, filters *data.WODFilter) sq.
 
 	return baseQuery
 }
+
+func processVisibilityFilter(baseQuery sq.SelectBuilder, filters *data.WODFilter, userID int) sq.SelectBuilder {
+	baseQuery = baseQuery.Where(visibleWODs(userID))
+
+	if len(filters.Visibility) > 0 {
+		baseQuery = baseQuery.Where(sq.Eq{"wod.visibility": filters.Visibility})
+	}
+
+	return baseQuery
+}
+
+// visibleWODs limits WODs to those the user can see: public WODs, their own, their Gyms', those shared
+// with them and those in programs assigned to them
+func visibleWODs(userID int) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{"wod.visibility": data.VisibilityPublic},
+		sq.Eq{"wod.created_by": userID},
+		sq.And{
+			sq.Eq{"wod.visibility": data.VisibilityGym},
+			sq.Select("gym_id").
+				From("gym_member").
+				Where(sq.Eq{"user_id": userID}).
+				Prefix("wod.gym_id IN (").
+				Suffix(")"),
+		},
+		sq.Select("wod_id").
+			From("wod_share_grant").
+			Where(sq.Eq{"user_id": userID}).
+			Prefix("wod.id IN (").
+			Suffix(")"),
+		sq.Select("program_workout.wod_id").
+			From("program_workout").
+			Join("program_assignment ON program_assignment.program_id = program_workout.program_id").
+			Where(assignedTo(userID)).
+			Prefix("wod.id IN (").
+			Suffix(")"),
+	}
+}
+
+// checkWODVisible returns sql.ErrNoRows unless the user can see the WOD
+func checkWODVisible(db queryer, wodID int, userID int) error {
+	selectQuery := psql.
+		Select("wod.id").
+		From("wod").
+		Where(sq.Eq{"wod.id": wodID}).
+		Where(visibleWODs(userID))
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var id int
+	return db.QueryRow(sqlQuery, args...).Scan(&id)
+}
+
+// processLoadFilter keeps WODs with a scaling option whose load (in kg) is within the filter's loads
+func processLoadFilter(baseQuery sq.SelectBuilder, filters *data.WODFilter) sq.SelectBuilder {
+	if filters.MinLoad == nil && filters.MaxLoad == nil {
+		return baseQuery
+	}
+
+	scalingQuery := sq.
+		Select("1").
+		From("wod_scaling").
+		Where("wod_scaling.wod_id = wod.id")
+
+	if filters.MinLoad != nil {
+		scalingQuery = scalingQuery.Where("wod_scaling.load >= ?", filters.MinLoad.Amount)
+	}
+
+	if filters.MaxLoad != nil {
+		scalingQuery = scalingQuery.Where("wod_scaling.load <= ?", filters.MaxLoad.Amount)
+	}
+
+	return baseQuery.Where(scalingQuery.Prefix("EXISTS (").Suffix(")"))
+}
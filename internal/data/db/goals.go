@@ -0,0 +1,271 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// CreateGoal will set a goal (score goals must be for a WOD the user can see)
+func CreateGoal(db *sql.DB, goal data.GoalInput, userID int) (int64, error) {
+	if goal.WODID != nil {
+		if err := checkWODVisible(db, *goal.WODID, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	insertQuery := psql.
+		Insert("goal").
+		Columns("user_id, name, type, wod_id, tier, target, measure, period, start_date, deadline, progress, status").
+		Values(userID, goal.Name, goal.Type, goal.WODID, goal.Tier, goal.Target, goal.Measure, goal.Period, goal.StartDate, goal.Deadline, 0, data.GoalOnTrack).
+		Suffix("RETURNING \"id\"")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	var goalID int64
+	err := db.QueryRow(sqlInsertQuery, args...).Scan(&goalID)
+	return goalID, err
+}
+
+// GetGoals will evaluate and return a user's goals as of now
+func GetGoals(db *sql.DB, userID int, now int64) ([]data.Goal, error) {
+	var dbGoals = []data.Goal{}
+
+	selectQuery := selectGoals(userID).
+		OrderBy("id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		dbGoals = append(dbGoals, goal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range dbGoals {
+		if err := evaluateGoal(db, &dbGoals[i], userID, now, loc); err != nil {
+			return nil, err
+		}
+	}
+
+	return dbGoals, nil
+}
+
+// GetGoal will evaluate and return an individual goal as of now
+func GetGoal(db *sql.DB, goalID string, userID int, now int64) (data.Goal, error) {
+	selectQuery := selectGoals(userID).
+		Where(sq.Eq{"id": goalID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return data.Goal{}, err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return data.Goal{}, sql.ErrNoRows
+	}
+
+	goal, err := scanGoal(rows)
+	if err != nil {
+		return goal, err
+	}
+	rows.Close()
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return goal, err
+	}
+
+	return goal, evaluateGoal(db, &goal, userID, now, loc)
+}
+
+// EvaluateGoals will update the progress and status of all of a user's goals as of now
+func EvaluateGoals(db *sql.DB, userID int, now int64) error {
+	_, err := GetGoals(db, userID, now)
+	return err
+}
+
+// UpdateGoal will change a goal, starting its evaluation afresh
+func UpdateGoal(db *sql.DB, goalID string, goal data.GoalInput, userID int) error {
+	if goal.WODID != nil {
+		if err := checkWODVisible(db, *goal.WODID, userID); err != nil {
+			return err
+		}
+	}
+
+	updateQuery := psql.
+		Update("goal").
+		Set("name", goal.Name).
+		Set("type", goal.Type).
+		Set("wod_id", goal.WODID).
+		Set("tier", goal.Tier).
+		Set("target", goal.Target).
+		Set("measure", goal.Measure).
+		Set("period", goal.Period).
+		Set("start_date", goal.StartDate).
+		Set("deadline", goal.Deadline).
+		Set("current", nil).
+		Set("progress", 0).
+		Set("status", data.GoalOnTrack).
+		Set("achieved_at", nil).
+		Where(sq.Eq{"id": goalID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteGoal will delete a goal
+func DeleteGoal(db *sql.DB, goalID string, userID int) error {
+	deleteQuery := psql.
+		Delete("goal").
+		Where(sq.Eq{"id": goalID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlDeleteQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// evaluateGoal works out a goal's progress from the user's Activities and stores it. Score goals stay achieved
+// once they have been.
+func evaluateGoal(db queryer, goal *data.Goal, userID int, now int64, loc *time.Location) error {
+	if goal.Type == data.GoalScore && goal.Status == data.GoalAchieved {
+		return nil
+	}
+
+	stats, err := goalStats(db, goal.GoalInput, userID, now, loc)
+	if err != nil {
+		return err
+	}
+
+	goal.Evaluate(stats, now, loc)
+
+	if goal.Type == data.GoalScore && goal.Status == data.GoalAchieved && goal.AchievedAt == nil {
+		goal.AchievedAt = &now
+	}
+
+	updateQuery := psql.
+		Update("goal").
+		Set("current", goal.Current).
+		Set("progress", goal.Progress).
+		Set("status", goal.Status).
+		Set("achieved_at", goal.AchievedAt).
+		Where(sq.Eq{"id": goal.ID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err = db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// goalStats reads the Activities a goal is evaluated against: the times of attempts at a score goal's WOD (at its
+// tier) since it was set, or the sessions in the current period (in the user's time zone) of other goals
+func goalStats(db queryer, goal data.GoalInput, userID int, now int64, loc *time.Location) (data.GoalStats, error) {
+	var stats data.GoalStats
+	at := goal.EvaluationTime(now)
+
+	if goal.Type == data.GoalScore {
+		selectQuery := psql.
+			Select("time_taken").
+			From("activity").
+			Where(sq.Eq{"user_id": userID}).
+			Where(sq.Eq{"wod_id": goal.WODID}).
+			Where(sq.Eq{"tier": goal.Tier}).
+			Where("date >= ?", goal.StartDate).
+			Where("date <= ?", at).
+			OrderBy("date, id")
+		sqlQuery, args, _ := selectQuery.ToSql()
+
+		rows, err := db.Query(sqlQuery, args...)
+		if err != nil {
+			return stats, err
+		}
+
+		defer rows.Close()
+		for rows.Next() {
+			var timeTaken int64
+			if err := rows.Scan(&timeTaken); err != nil {
+				return stats, err
+			}
+			stats.Times = append(stats.Times, timeTaken)
+		}
+
+		return stats, rows.Err()
+	}
+
+	period := data.PeriodWeek
+	if goal.Period != nil {
+		period = *goal.Period
+	}
+	start, end := data.PeriodBounds(period, at, loc)
+
+	selectQuery := psql.
+		Select("COUNT(*), COALESCE(SUM(time_taken), 0), COALESCE(SUM(meps), 0)").
+		From("activity").
+		Where(sq.Eq{"user_id": userID}).
+		Where("date >= ?", start).
+		Where("date < ?", end).
+		Where("date >= ?", goal.StartDate)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	err := db.QueryRow(sqlQuery, args...).Scan(&stats.Count, &stats.TimeTaken, &stats.MEPs)
+	return stats, err
+}
+
+// selectGoals builds the query for a user's goals (score goals set before they had a tier are for Rx)
+func selectGoals(userID int) sq.SelectBuilder {
+	return psql.
+		Select("id, name, type, wod_id").
+		Column("CASE WHEN type = ? THEN COALESCE(tier, ?) END", data.GoalScore, data.TierRx).
+		Column("target, measure, period, start_date, deadline, current, progress, status, achieved_at").
+		From("goal").
+		Where(sq.Eq{"user_id": userID})
+}
+
+// scanGoal reads a row produced by selectGoals
+func scanGoal(rows *sql.Rows) (data.Goal, error) {
+	var goal data.Goal
+
+	err := rows.Scan(&goal.ID, &goal.Name, &goal.Type, &goal.WODID, &goal.Tier, &goal.Target, &goal.Measure, &goal.Period,
+		&goal.StartDate, &goal.Deadline, &goal.Current, &goal.Progress, &goal.Status, &goal.AchievedAt)
+	return goal, err
+}
@@ -0,0 +1,145 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// CreateGroup will create a group of athletes coached by the user
+func CreateGroup(db *sql.DB, group data.GroupInput, userID int) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	groupQuery := psql.
+		Insert("athlete_group").
+		Columns("name, coach_id").
+		Values(group.Name, userID).
+		Suffix("RETURNING \"id\"")
+	sqlGroupQuery, args, _ := groupQuery.ToSql()
+
+	var groupID int
+	err = tx.QueryRow(sqlGroupQuery, args...).Scan(&groupID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertGroupMembers(tx, groupID, group.MemberIDs); err != nil {
+		return 0, err
+	}
+
+	return groupID, tx.Commit()
+}
+
+// GetGroups will get and return the groups coached by the user
+func GetGroups(db *sql.DB, userID int) ([]data.Group, error) {
+	var dbGroups = []data.Group{}
+
+	selectQuery := psql.
+		Select("athlete_group.id, name, coach_id, ARRAY_REMOVE(ARRAY_AGG(athlete_group_member.user_id), NULL)").
+		From("athlete_group").
+		LeftJoin("athlete_group_member ON athlete_group_member.group_id = athlete_group.id").
+		Where(sq.Eq{"coach_id": userID}).
+		GroupBy("athlete_group.id").
+		OrderBy("athlete_group.id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var group data.Group
+		var memberIDs pq.Int64Array
+
+		if err := rows.Scan(&group.ID, &group.Name, &group.CoachID, &memberIDs); err != nil {
+			return nil, err
+		}
+
+		group.MemberIDs = []int{}
+		for _, memberID := range memberIDs {
+			group.MemberIDs = append(group.MemberIDs, int(memberID))
+		}
+
+		dbGroups = append(dbGroups, group)
+	}
+
+	return dbGroups, nil
+}
+
+// AddGroupMembers will add athletes to a group coached by the user
+func AddGroupMembers(db *sql.DB, groupID string, memberIDs []int, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	id, err := getCoachedGroup(tx, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := insertGroupMembers(tx, id, memberIDs); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveGroupMember will remove an athlete from a group coached by the user
+func RemoveGroupMember(db *sql.DB, groupID string, memberID string, userID int) error {
+	id, err := getCoachedGroup(db, groupID, userID)
+	if err != nil {
+		return err
+	}
+
+	deleteQuery := psql.
+		Delete("athlete_group_member").
+		Where(sq.Eq{"group_id": id}).
+		Where(sq.Eq{"user_id": memberID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	_, err = db.Exec(sqlDeleteQuery, args...)
+	return err
+}
+
+// getCoachedGroup checks the group is coached by the user and returns its ID
+func getCoachedGroup(db queryer, groupID string, userID int) (int, error) {
+	selectQuery := psql.
+		Select("id").
+		From("athlete_group").
+		Where(sq.Eq{"id": groupID}).
+		Where(sq.Eq{"coach_id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var id int
+	err := db.QueryRow(sqlQuery, args...).Scan(&id)
+	return id, err
+}
+
+func insertGroupMembers(db queryer, groupID int, memberIDs []int) error {
+	if len(memberIDs) == 0 {
+		return nil
+	}
+
+	insertQuery := psql.
+		Insert("athlete_group_member").
+		Columns("group_id, user_id").
+		Suffix("ON CONFLICT DO NOTHING")
+	for _, memberID := range memberIDs {
+		insertQuery = insertQuery.Values(groupID, memberID)
+	}
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlInsertQuery, args...)
+	return err
+}
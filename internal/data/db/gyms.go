@@ -0,0 +1,391 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// ErrForbidden is returned when the user doesn't have the Gym role needed
+var ErrForbidden = errors.New("forbidden")
+
+// ErrLastGymOwner is returned when a change would leave a Gym without an owner (including deleting the account of
+// the only owner of a Gym that has other members)
+var ErrLastGymOwner = errors.New("last owner of a gym")
+
+// CreateGym will create a Gym owned by the user
+func CreateGym(db *sql.DB, gym data.GymInput, userID int) (int, error) {
+	inviteCode, err := data.NewInviteCode()
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	gymQuery := psql.
+		Insert("gym").
+		Columns("name, invite_code, created_by").
+		Values(gym.Name, inviteCode, userID).
+		Suffix("RETURNING \"id\"")
+	sqlGymQuery, args, _ := gymQuery.ToSql()
+
+	var gymID int
+	err = tx.QueryRow(sqlGymQuery, args...).Scan(&gymID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := insertGymMember(tx, gymID, userID, data.GymRoleOwner); err != nil {
+		return 0, err
+	}
+
+	return gymID, tx.Commit()
+}
+
+// GetGyms will get and return the Gyms the user is a member of (with invite codes for those they run)
+func GetGyms(db *sql.DB, userID int) ([]data.Gym, error) {
+	var dbGyms = []data.Gym{}
+
+	selectQuery := psql.
+		Select("gym.id, gym.name, gym_member.role, gym.invite_code").
+		From("gym").
+		Join("gym_member ON gym_member.gym_id = gym.id").
+		Where(sq.Eq{"gym_member.user_id": userID}).
+		OrderBy("gym.name")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var gym data.Gym
+
+		if err := rows.Scan(&gym.ID, &gym.Name, &gym.Role, &gym.InviteCode); err != nil {
+			return nil, err
+		}
+
+		if gym.Role == data.GymRoleAthlete {
+			gym.InviteCode = nil
+		}
+
+		dbGyms = append(dbGyms, gym)
+	}
+
+	return dbGyms, nil
+}
+
+// GetGymRole will return the user's role in a Gym (sql.ErrNoRows if they aren't a member)
+func GetGymRole(db *sql.DB, gymID string, userID int) (string, error) {
+	return getGymRole(db, gymID, userID)
+}
+
+// GetGymMembers will get and return the members of a Gym the user belongs to
+func GetGymMembers(db *sql.DB, gymID string, userID int) ([]data.GymMember, error) {
+	var dbMembers = []data.GymMember{}
+
+	if _, err := getGymRole(db, gymID, userID); err != nil {
+		return nil, err
+	}
+
+	selectQuery := psql.
+		Select("gym_member.user_id, \"user\".username, gym_member.role").
+		From("gym_member").
+		Join("\"user\" ON \"user\".id = gym_member.user_id").
+		Where(sq.Eq{"gym_member.gym_id": gymID}).
+		OrderBy("\"user\".username")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var member data.GymMember
+
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role); err != nil {
+			return nil, err
+		}
+
+		dbMembers = append(dbMembers, member)
+	}
+
+	return dbMembers, nil
+}
+
+// JoinGym will add the user to the Gym with the invite code as an athlete
+func JoinGym(db *sql.DB, inviteCode string, userID int) (int, error) {
+	selectQuery := psql.
+		Select("id").
+		From("gym").
+		Where(sq.Eq{"invite_code": inviteCode})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var gymID int
+	err := db.QueryRow(sqlQuery, args...).Scan(&gymID)
+	if err != nil {
+		return 0, err
+	}
+
+	return gymID, insertGymMember(db, gymID, userID, data.GymRoleAthlete)
+}
+
+// RotateInviteCode will replace a Gym's invite code (owners and coaches only)
+func RotateInviteCode(db *sql.DB, gymID string, userID int) (string, error) {
+	role, err := getGymRole(db, gymID, userID)
+	if err != nil {
+		return "", err
+	} else if role == data.GymRoleAthlete {
+		return "", ErrForbidden
+	}
+
+	inviteCode, err := data.NewInviteCode()
+	if err != nil {
+		return "", err
+	}
+
+	updateQuery := psql.
+		Update("gym").
+		Set("invite_code", inviteCode).
+		Where(sq.Eq{"id": gymID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err = db.Exec(sqlUpdateQuery, args...)
+	return inviteCode, err
+}
+
+// UpdateGymMemberRole will change a member's role in a Gym (owners only, and the last owner can't stop being one),
+// returning the role they had before
+func UpdateGymMemberRole(dataSource *sql.DB, gymID string, memberID string, role string, userID int) (string, error) {
+	db, err := dataSource.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer db.Rollback()
+
+	userRole, err := getGymRole(db, gymID, userID)
+	if err != nil {
+		return "", err
+	} else if userRole != data.GymRoleOwner {
+		return "", ErrForbidden
+	}
+
+	memberRole, err := getGymMemberRole(db, gymID, memberID)
+	if err != nil {
+		return "", err
+	}
+
+	if memberRole == data.GymRoleOwner && role != data.GymRoleOwner {
+		if err := checkOtherOwners(db, gymID, memberID); err != nil {
+			return "", err
+		}
+	}
+
+	updateQuery := psql.
+		Update("gym_member").
+		Set("role", role).
+		Where(sq.Eq{"gym_id": gymID}).
+		Where(sq.Eq{"user_id": memberID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return "", err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return "", sql.ErrNoRows
+	}
+
+	return memberRole, db.Commit()
+}
+
+// RemoveGymMember will remove a member from a Gym (owners can remove anyone, members can leave, but the last owner
+// can't)
+func RemoveGymMember(dataSource *sql.DB, gymID string, memberID int, userID int) error {
+	db, err := dataSource.Begin()
+	if err != nil {
+		return err
+	}
+	defer db.Rollback()
+
+	userRole, err := getGymRole(db, gymID, userID)
+	if err != nil {
+		return err
+	} else if userRole != data.GymRoleOwner && memberID != userID {
+		return ErrForbidden
+	}
+
+	memberRole, err := getGymMemberRole(db, gymID, strconv.Itoa(memberID))
+	if err != nil {
+		return err
+	} else if memberRole == data.GymRoleOwner {
+		if err := checkOtherOwners(db, gymID, strconv.Itoa(memberID)); err != nil {
+			return err
+		}
+	}
+
+	deleteQuery := psql.
+		Delete("gym_member").
+		Where(sq.Eq{"gym_id": gymID}).
+		Where(sq.Eq{"user_id": memberID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlDeleteQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return db.Commit()
+}
+
+// GetLeaderboard will get and return each Gym member's best time for a WOD, fastest first (only at tier, in
+// division and for people the user follows if asked). Attempts without a tier aren't ranked.
+func GetLeaderboard(db *sql.DB, gymID string, wodID string, filters *data.LeaderboardFilter, userID int) ([]data.LeaderboardEntry, error) {
+	var dbEntries = []data.LeaderboardEntry{}
+
+	if _, err := getGymRole(db, gymID, userID); err != nil {
+		return nil, err
+	}
+
+	bestQuery := sq.
+		Select("DISTINCT ON (activity.user_id) activity.user_id, \"user\".username, activity.id, activity.date, activity.time_taken").
+		From("activity").
+		Join("gym_member ON gym_member.user_id = activity.user_id").
+		Join("\"user\" ON \"user\".id = activity.user_id").
+		Join("wod ON wod.id = activity.wod_id").
+		Where(sq.Eq{"gym_member.gym_id": gymID}).
+		Where(sq.Eq{"activity.wod_id": wodID}).
+		Where(visibleWODs(userID)).
+		Where(sq.Or{sq.Eq{"activity.user_id": userID}, notBlocked("activity.user_id", userID)}).
+		Where(sq.NotEq{"activity.tier": nil}).
+		OrderBy("activity.user_id, activity.time_taken, activity.date")
+
+	if filters.Tier != "" {
+		bestQuery = bestQuery.Where(sq.Eq{"activity.tier": filters.Tier})
+	}
+
+	if filters.Division != "" {
+		bestQuery = bestQuery.Where(sq.Eq{"\"user\".division": filters.Division})
+	}
+
+	if filters.Following != nil && *filters.Following {
+		bestQuery = bestQuery.Where(sq.Or{sq.Eq{"activity.user_id": userID}, followedBy("activity.user_id", userID)})
+	}
+
+	selectQuery := psql.
+		Select("*").
+		FromSelect(bestQuery, "best").
+		OrderBy("time_taken, date")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var entry data.LeaderboardEntry
+
+		if err := rows.Scan(&entry.UserID, &entry.Username, &entry.ActivityID, &entry.Date, &entry.TimeTaken); err != nil {
+			return nil, err
+		}
+
+		entry.Rank = len(dbEntries) + 1
+		if previous := len(dbEntries) - 1; previous >= 0 && dbEntries[previous].TimeTaken == entry.TimeTaken {
+			entry.Rank = dbEntries[previous].Rank
+		}
+
+		dbEntries = append(dbEntries, entry)
+	}
+
+	return dbEntries, nil
+}
+
+func getGymRole(db queryer, gymID string, userID int) (string, error) {
+	selectQuery := psql.
+		Select("role").
+		From("gym_member").
+		Where(sq.Eq{"gym_id": gymID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var role string
+	err := db.QueryRow(sqlQuery, args...).Scan(&role)
+	return role, err
+}
+
+func getGymMemberRole(db queryer, gymID string, memberID string) (string, error) {
+	selectQuery := psql.
+		Select("role").
+		From("gym_member").
+		Where(sq.Eq{"gym_id": gymID}).
+		Where(sq.Eq{"user_id": memberID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var role string
+	err := db.QueryRow(sqlQuery, args...).Scan(&role)
+	return role, err
+}
+
+// checkOtherOwners returns ErrLastGymOwner unless a Gym has an owner other than the member. The Gym is locked until
+// the transaction ends so two owners can't both stop being one at once.
+func checkOtherOwners(db queryer, gymID string, memberID string) error {
+	lockQuery := psql.
+		Select("id").
+		From("gym").
+		Where(sq.Eq{"id": gymID}).
+		Suffix("FOR UPDATE")
+	sqlLockQuery, lockArgs, _ := lockQuery.ToSql()
+
+	var id int
+	if err := db.QueryRow(sqlLockQuery, lockArgs...).Scan(&id); err != nil {
+		return err
+	}
+
+	selectQuery := psql.
+		Select("COUNT(*)").
+		From("gym_member").
+		Where(sq.Eq{"gym_id": gymID}).
+		Where(sq.Eq{"role": data.GymRoleOwner}).
+		Where(sq.NotEq{"user_id": memberID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var owners int
+	if err := db.QueryRow(sqlQuery, args...).Scan(&owners); err != nil {
+		return err
+	} else if owners == 0 {
+		return ErrLastGymOwner
+	}
+
+	return nil
+}
+
+func insertGymMember(db queryer, gymID int, userID int, role string) error {
+	insertQuery := psql.
+		Insert("gym_member").
+		Columns("gym_id, user_id, role").
+		Values(gymID, userID, role).
+		Suffix("ON CONFLICT DO NOTHING")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlInsertQuery, args...)
+	return err
+}
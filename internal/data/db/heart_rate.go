@@ -0,0 +1,139 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/wearable"
+)
+
+// GetMaxHeartRate will return the max heart rate a user has set (nil if they haven't)
+func GetMaxHeartRate(db *sql.DB, userID int) (*int, error) {
+	selectQuery := psql.
+		Select("max_heart_rate").
+		From("\"user\"").
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var maxHeartRate *int
+	err := db.QueryRow(sqlQuery, args...).Scan(&maxHeartRate)
+	return maxHeartRate, err
+}
+
+// UpdateMaxHeartRate will change the max heart rate a user's zones are worked out from
+func UpdateMaxHeartRate(db *sql.DB, maxHeartRate int, userID int) error {
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("max_heart_rate", maxHeartRate).
+		Where(sq.Eq{"id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// CreateActivityWithHeartRate will create an Activity for a WOD the user can see along with the summary of the
+// heart rate recorded during it, and return its ID
+func CreateActivityWithHeartRate(db *sql.DB, activity data.ActivityInput, summary wearable.Summary, userID int) (int64, error) {
+	if err := checkWODVisible(db, *activity.WODID, userID); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	activityID, err := insertActivity(tx, activity, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := saveHeartRate(tx, activityID, summary); err != nil {
+		return 0, err
+	}
+
+	return activityID, tx.Commit()
+}
+
+// AddHeartRate will store the summary of the heart rate recorded during one of the user's Activities (replacing
+// any already stored), filling in its MEPs if they weren't given
+func AddHeartRate(db *sql.DB, activityID int64, summary wearable.Summary, userID int) error {
+	if err := checkActivityOwner(db, activityID, userID); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := saveHeartRate(tx, activityID, summary); err != nil {
+		return err
+	}
+
+	updateQuery := psql.
+		Update("activity").
+		Set("meps", summary.MEPs).
+		Where(sq.Eq{"id": activityID}).
+		Where(sq.Eq{"meps": nil})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	if _, err := tx.Exec(sqlUpdateQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetHeartRate will get and return the heart rate summary of one of the user's Activities
+func GetHeartRate(db *sql.DB, activityID string, userID int) (data.HeartRate, error) {
+	var heartRate data.HeartRate
+
+	selectQuery := psql.
+		Select("heart_rate.activity_id, start, duration, max_heart_rate, average_heart_rate, peak_heart_rate, " +
+			"heart_rate.meps, trimp, time_in_zone, series_interval, series").
+		From("activity_heart_rate heart_rate").
+		Join("activity ON activity.id = heart_rate.activity_id").
+		Where(sq.Eq{"heart_rate.activity_id": activityID}).
+		Where(sq.Eq{"activity.user_id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var timeInZone, series pq.Int64Array
+	err := db.QueryRow(sqlQuery, args...).Scan(
+		&heartRate.ActivityID, &heartRate.Start, &heartRate.Duration, &heartRate.MaxHeartRate,
+		&heartRate.AverageHeartRate, &heartRate.PeakHeartRate, &heartRate.MEPs, &heartRate.TRIMP,
+		&timeInZone, &heartRate.SeriesInterval, &series,
+	)
+	if err != nil {
+		return heartRate, err
+	}
+
+	heartRate.TimeInZone = timeInZone
+	heartRate.Series = series
+
+	return heartRate, nil
+}
+
+func saveHeartRate(db queryer, activityID int64, summary wearable.Summary) error {
+	insertQuery := psql.
+		Insert("activity_heart_rate").
+		Columns("activity_id, start, duration, max_heart_rate, average_heart_rate, peak_heart_rate, meps, trimp, "+
+			"time_in_zone, series_interval, series").
+		Values(activityID, summary.Start, summary.Duration, summary.MaxHeartRate, summary.AverageHeartRate,
+			summary.PeakHeartRate, summary.MEPs, summary.TRIMP, pq.Int64Array(summary.TimeInZone),
+			summary.SeriesInterval, pq.Int64Array(summary.Series)).
+		Suffix("ON CONFLICT (activity_id) DO UPDATE SET start = EXCLUDED.start, duration = EXCLUDED.duration, " +
+			"max_heart_rate = EXCLUDED.max_heart_rate, average_heart_rate = EXCLUDED.average_heart_rate, " +
+			"peak_heart_rate = EXCLUDED.peak_heart_rate, meps = EXCLUDED.meps, trimp = EXCLUDED.trimp, " +
+			"time_in_zone = EXCLUDED.time_in_zone, series_interval = EXCLUDED.series_interval, series = EXCLUDED.series")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlInsertQuery, args...)
+	return err
+}
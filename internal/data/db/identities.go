@@ -0,0 +1,275 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/oidc"
+)
+
+// ErrIdentityLinked is returned when linking an identity that's already linked to another user
+var ErrIdentityLinked = errors.New("identity linked to another user")
+
+// ErrNoLinkedUser is returned when nobody is linked to an identity and accounts aren't created at single sign-on
+var ErrNoLinkedUser = errors.New("no user linked to identity")
+
+// CreateOIDCLogin will keep a login with the single sign-on provider (under the hash of its state) until the user
+// comes back from the provider or ttl passes
+func CreateOIDCLogin(db *sql.DB, state string, login data.OIDCLogin, ttl time.Duration) error {
+	now := time.Now()
+
+	insertQuery := psql.
+		Insert("oidc_login").
+		Columns("state_hash, nonce, code_verifier, link_user_id, created_at, expires_at").
+		Values(data.HashToken(state), login.Nonce, login.CodeVerifier, login.LinkUserID, now.Unix(), now.Add(ttl).Unix())
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return err
+}
+
+// UseOIDCLogin will mark the unexpired login with state as used and return it (or sql.ErrNoRows if there's no
+// such login), so each can only be finished once
+func UseOIDCLogin(db *sql.DB, state string) (data.OIDCLogin, error) {
+	now := time.Now().Unix()
+
+	updateQuery := psql.
+		Update("oidc_login").
+		Set("used_at", now).
+		Where(sq.Eq{"state_hash": data.HashToken(state), "used_at": nil}).
+		Where(sq.Gt{"expires_at": now}).
+		Suffix("RETURNING nonce, code_verifier, link_user_id")
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	var login data.OIDCLogin
+	err := db.QueryRow(sqlQuery, args...).Scan(&login.Nonce, &login.CodeVerifier, &login.LinkUserID)
+	return login, err
+}
+
+// GetIdentityUser will get and return the user an identity is linked to. An identity nobody is linked to yet is
+// linked to the user with the same verified email address, or (if provision is set) to a new account.
+func GetIdentityUser(db *sql.DB, identity oidc.Identity, provision bool) (data.User, error) {
+	var user data.User
+
+	tx, err := db.Begin()
+	if err != nil {
+		return user, err
+	}
+	defer tx.Rollback()
+
+	email := data.NormaliseEmail(identity.Email)
+
+	selectQuery := psql.
+		Select("\"user\".id, \"user\".username, \"user\".role").
+		From("user_identity").
+		Join("\"user\" ON \"user\".id = user_identity.user_id").
+		Where(sq.Eq{"user_identity.issuer": identity.Issuer, "user_identity.subject": identity.Subject})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	err = tx.QueryRow(sqlQuery, args...).Scan(&user.ID, &user.Username, &user.Role)
+	if err == nil {
+		updateQuery := psql.
+			Update("user_identity").
+			SetMap(map[string]interface{}{"email": emptyToNull(email), "last_login_at": time.Now().Unix()}).
+			Where(sq.Eq{"issuer": identity.Issuer, "subject": identity.Subject})
+		sqlUpdateQuery, updateArgs, _ := updateQuery.ToSql()
+
+		if _, err := tx.Exec(sqlUpdateQuery, updateArgs...); err != nil {
+			return user, err
+		}
+
+		return user, tx.Commit()
+	} else if err != sql.ErrNoRows {
+		return user, err
+	}
+
+	// Only link by email when both the provider and this service have checked the address, so nobody can take
+	// over an account by putting its email address on an identity elsewhere
+	found := false
+	if identity.EmailVerified && data.ValidEmail(email) {
+		selectQuery := psql.
+			Select("id, username, role").
+			From("\"user\"").
+			Where(sq.Eq{"email": email, "email_verified": true})
+		sqlQuery, args, _ := selectQuery.ToSql()
+
+		err = tx.QueryRow(sqlQuery, args...).Scan(&user.ID, &user.Username, &user.Role)
+		if err == nil {
+			found = true
+		} else if err != sql.ErrNoRows {
+			return user, err
+		}
+	}
+
+	if !found {
+		if !provision {
+			return user, ErrNoLinkedUser
+		}
+
+		user, err = createIdentityUser(tx, identity, email)
+		if err != nil {
+			return user, err
+		}
+	}
+
+	if _, err := linkIdentity(tx, identity, email, user.ID); err != nil {
+		return user, err
+	}
+
+	return user, tx.Commit()
+}
+
+// LinkIdentity will link an identity to the user so they can log in with it
+func LinkIdentity(db *sql.DB, identity oidc.Identity, userID int) error {
+	linkedID, err := linkIdentity(db, identity, data.NormaliseEmail(identity.Email), userID)
+	if err != nil {
+		return err
+	} else if linkedID != userID {
+		return ErrIdentityLinked
+	}
+
+	return nil
+}
+
+// GetIdentities will get and return the identities linked to the user
+func GetIdentities(db *sql.DB, userID int) ([]data.Identity, error) {
+	var dbIdentities = []data.Identity{}
+
+	selectQuery := psql.
+		Select("id, issuer, subject, email, created_at, last_login_at").
+		From("user_identity").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("created_at, id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var identity data.Identity
+
+		if err := rows.Scan(&identity.ID, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt, &identity.LastLoginAt); err != nil {
+			return nil, err
+		}
+
+		dbIdentities = append(dbIdentities, identity)
+	}
+
+	return dbIdentities, nil
+}
+
+// UnlinkIdentity will stop the user logging in with an identity
+func UnlinkIdentity(db *sql.DB, identityID string, userID int) error {
+	deleteQuery := psql.
+		Delete("user_identity").
+		Where(sq.Eq{"id": identityID, "user_id": userID})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// linkIdentity links an identity to the user unless it's already linked, returning who it's linked to
+func linkIdentity(db queryer, identity oidc.Identity, email string, userID int) (int, error) {
+	insertQuery := psql.
+		Insert("user_identity").
+		Columns("issuer, subject, user_id, email, created_at").
+		Values(identity.Issuer, identity.Subject, userID, emptyToNull(email), time.Now().Unix()).
+		Suffix("ON CONFLICT (issuer, subject) DO UPDATE SET user_id = user_identity.user_id RETURNING user_id")
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	var linkedID int
+	err := db.QueryRow(sqlQuery, args...).Scan(&linkedID)
+	return linkedID, err
+}
+
+// createIdentityUser creates an athlete account for someone logging in with an identity for the first time. They
+// get a random password (so can only log in with a password after resetting it) and the identity's email address
+// unless someone else already has it.
+func createIdentityUser(db queryer, identity oidc.Identity, email string) (data.User, error) {
+	user := data.User{Role: data.RoleAthlete}
+
+	username, err := unusedUsername(db, data.NewUsername(identity.PreferredUsername, email))
+	if err != nil {
+		return user, err
+	}
+	user.Username = username
+
+	_, password, err := data.NewToken()
+	if err != nil {
+		return user, err
+	}
+
+	values := map[string]interface{}{
+		"username": user.Username,
+		"password": password,
+		"role":     user.Role,
+	}
+
+	if data.ValidEmail(email) {
+		if _, err := GetUserIDByEmail(db, email); err == sql.ErrNoRows {
+			values["email"] = email
+			values["email_verified"] = identity.EmailVerified
+		} else if err != nil {
+			return user, err
+		}
+	}
+
+	if identity.Name != "" && data.ValidDisplayName(identity.Name) {
+		values["display_name"] = identity.Name
+	}
+
+	insertQuery := psql.
+		Insert("\"user\"").
+		SetMap(values).
+		Suffix("RETURNING \"id\"")
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	err = db.QueryRow(sqlQuery, args...).Scan(&user.ID)
+	return user, err
+}
+
+// unusedUsername returns username, or username with the lowest number after it that nobody has
+func unusedUsername(db queryer, username string) (string, error) {
+	candidate := username
+
+	for n := 2; ; n++ {
+		selectQuery := psql.
+			Select().
+			Column("EXISTS (SELECT 1 FROM \"user\" WHERE username = ?)", candidate)
+		sqlQuery, args, _ := selectQuery.ToSql()
+
+		var taken bool
+		if err := db.QueryRow(sqlQuery, args...).Scan(&taken); err != nil {
+			return "", err
+		} else if !taken {
+			return candidate, nil
+		}
+
+		suffix := fmt.Sprintf("-%d", n)
+		base := username
+		if len(base)+len(suffix) > data.MaxUsernameLength {
+			base = base[:data.MaxUsernameLength-len(suffix)]
+		}
+		candidate = base + suffix
+	}
+}
@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// ImportActivities will add every row as an Activity, matching each to an existing WOD by its exercise text
+// or creating a new one. All rows are added in a single transaction which is only committed if every row
+// succeeds and dryRun is false.
+func ImportActivities(db *sql.DB, rows []data.ImportRow, userID int, dryRun bool) (data.ImportReport, error) {
+	report := data.ImportReport{DryRun: dryRun, Rows: len(rows)}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return report, err
+	}
+	defer tx.Rollback()
+
+	wodIDs, err := getWODIDsByExercise(tx, userID)
+	if err != nil {
+		return report, err
+	}
+
+	failed := false
+	for _, row := range rows {
+		result, err := importRow(tx, row, userID, wodIDs)
+		if err != nil {
+			return report, err
+		} else if result.Error != "" {
+			failed = true
+		} else {
+			report.ActivitiesCreated++
+			if result.NewWOD {
+				report.WODsCreated++
+			}
+		}
+
+		report.Results = append(report.Results, result)
+	}
+
+	if dryRun || failed {
+		// Nothing was kept so only IDs of existing WODs mean anything
+		for i := range report.Results {
+			report.Results[i].ActivityID = nil
+			if report.Results[i].NewWOD {
+				report.Results[i].WODID = nil
+			}
+		}
+		return report, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return report, err
+	}
+	report.Committed = true
+
+	return report, nil
+}
+
+// importRow adds a single row inside its own savepoint so a failure can be reported without aborting the
+// rest of the import. An error is only returned if the savepoint itself fails, as the transaction can't be used
+// after that.
+func importRow(tx *sql.Tx, row data.ImportRow, userID int, wodIDs map[string]int) (data.ImportRowResult, error) {
+	result := data.ImportRowResult{Line: row.Line}
+
+	if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+		return result, err
+	}
+
+	key := data.NormaliseExercise(*row.WOD.Exercise)
+	wodID, exists := wodIDs[key]
+	if !exists {
+		newWODID, err := insertWOD(tx, row.WOD, userID)
+		if err != nil {
+			result.Error = err.Error()
+			return result, rollbackRow(tx)
+		}
+
+		wodID = newWODID
+		result.NewWOD = true
+	}
+
+	activity := row.Activity
+	activity.WODID = &wodID
+
+	activityID, err := insertActivity(tx, activity, userID)
+	if err != nil {
+		result.Error = err.Error()
+		return result, rollbackRow(tx)
+	}
+
+	if _, err := tx.Exec("RELEASE SAVEPOINT import_row"); err != nil {
+		return result, err
+	}
+
+	if result.NewWOD {
+		wodIDs[key] = wodID
+	}
+	result.WODID = &wodID
+	result.ActivityID = &activityID
+
+	return result, nil
+}
+
+// rollbackRow undoes a failed row and releases its savepoint so they don't pile up over a long import
+func rollbackRow(tx *sql.Tx) error {
+	if _, err := tx.Exec("ROLLBACK TO SAVEPOINT import_row"); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec("RELEASE SAVEPOINT import_row")
+	return err
+}
+
+// getWODIDsByExercise maps the normalised exercise text of every WOD the user can see to its ID
+func getWODIDsByExercise(db queryer, userID int) (map[string]int, error) {
+	wodIDs := map[string]int{}
+
+	selectQuery := psql.
+		Select("id, wod").
+		From("wod").
+		Where("wod IS NOT NULL").
+		Where(visibleWODs(userID)).
+		OrderBy("id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var wodID int
+		var exercise string
+
+		if err := rows.Scan(&wodID, &exercise); err != nil {
+			return nil, err
+		}
+
+		key := data.NormaliseExercise(exercise)
+		if _, exists := wodIDs[key]; !exists {
+			wodIDs[key] = wodID
+		}
+	}
+
+	return wodIDs, rows.Err()
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// CreateLift will log a lift (attached to one of the user's Activities if given) with its load in kilograms
+func CreateLift(db *sql.DB, lift data.LiftInput, userID int) (int64, error) {
+	if lift.ActivityID != nil {
+		if err := checkActivityOwner(db, *lift.ActivityID, userID); err != nil {
+			return 0, err
+		}
+	}
+
+	insertQuery := psql.
+		Insert("lift").
+		Columns("user_id, activity_id, movement, date, sets, reps, load, notes").
+		Values(userID, lift.ActivityID, data.NormaliseExercise(lift.Movement), lift.Date, lift.Sets, lift.Reps, lift.Load.Amount, lift.Notes).
+		Suffix("RETURNING \"id\"")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	var liftID int64
+	err := db.QueryRow(sqlInsertQuery, args...).Scan(&liftID)
+	return liftID, err
+}
+
+// GetLifts will get and return a user's lifts, oldest first, with one rep maxes estimated using the filter's formula
+// (loads are in kilograms)
+func GetLifts(db *sql.DB, filters *data.LiftFilter, userID int) ([]data.Lift, error) {
+	var dbLifts = []data.Lift{}
+
+	selectQuery := psql.
+		Select("id, activity_id, movement, date, sets, reps, load, notes").
+		From("lift").
+		Where(sq.Eq{"user_id": userID}).
+		OrderBy("date, id")
+	selectQuery = processLiftFilters(selectQuery, filters)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var lift data.Lift
+
+		if err := rows.Scan(&lift.ID, &lift.ActivityID, &lift.Movement, &lift.Date, &lift.Sets, &lift.Reps, &lift.Load.Amount, &lift.Notes); err != nil {
+			return nil, err
+		}
+
+		lift.Load.Unit = units.Kilograms
+
+		lift.EstimatedOneRM = data.EstimateOneRM(filters.Formula, lift.Load, lift.Reps)
+
+		dbLifts = append(dbLifts, lift)
+	}
+
+	return dbLifts, nil
+}
+
+// DeleteLift will delete one of a user's lifts
+func DeleteLift(db *sql.DB, liftID string, userID int) error {
+	deleteQuery := psql.
+		Delete("lift").
+		Where(sq.Eq{"id": liftID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlDeleteQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// checkActivityOwner returns sql.ErrNoRows if the Activity doesn't exist or belongs to someone else
+func checkActivityOwner(db queryer, activityID int64, userID int) error {
+	selectQuery := psql.
+		Select("id").
+		From("activity").
+		Where(sq.Eq{"id": activityID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var id int64
+	return db.QueryRow(sqlQuery, args...).Scan(&id)
+}
+
+func processLiftFilters(baseQuery sq.SelectBuilder, filters *data.LiftFilter) sq.SelectBuilder {
+	if filters.Movement != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"movement": data.NormaliseExercise(filters.Movement)})
+	}
+
+	if filters.ActivityID != nil {
+		baseQuery = baseQuery.Where(sq.Eq{"activity_id": *filters.ActivityID})
+	}
+
+	if !filters.StartDate.IsZero() {
+		baseQuery = baseQuery.Where("date >= ?", filters.StartDate.Unix())
+	}
+
+	if !filters.EndDate.IsZero() {
+		baseQuery = baseQuery.Where("date <= ?", filters.EndDate.Unix())
+	}
+
+	return baseQuery
+}
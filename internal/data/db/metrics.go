@@ -0,0 +1,151 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// CreateMetric will record a body metric or benchmark test
+func CreateMetric(db *sql.DB, metric data.MetricInput, userID int) (int64, error) {
+	insertQuery := psql.
+		Insert("metric").
+		Columns("user_id, type, name, value, unit, date, notes").
+		Values(userID, metric.Type, metric.Name, metric.Value, metric.Unit, metric.Date, metric.Notes).
+		Suffix("RETURNING \"id\"")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	var metricID int64
+	err := db.QueryRow(sqlInsertQuery, args...).Scan(&metricID)
+	return metricID, err
+}
+
+// GetMetrics will get and return a user's metrics, oldest first
+func GetMetrics(db *sql.DB, filters *data.MetricFilter, userID int) ([]data.Metric, error) {
+	var dbMetrics = []data.Metric{}
+
+	selectQuery := selectMetrics(userID).
+		OrderBy("date, id")
+	selectQuery = processMetricFilters(selectQuery, filters)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		metric, err := scanMetric(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		dbMetrics = append(dbMetrics, metric)
+	}
+
+	return dbMetrics, nil
+}
+
+// GetMetric will get and return an individual metric
+func GetMetric(db *sql.DB, metricID string, userID int) (data.Metric, error) {
+	selectQuery := selectMetrics(userID).
+		Where(sq.Eq{"id": metricID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return data.Metric{}, err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return data.Metric{}, sql.ErrNoRows
+	}
+
+	return scanMetric(rows)
+}
+
+// UpdateMetric will change a metric
+func UpdateMetric(db *sql.DB, metricID string, metric data.MetricInput, userID int) error {
+	updateQuery := psql.
+		Update("metric").
+		Set("type", metric.Type).
+		Set("name", metric.Name).
+		Set("value", metric.Value).
+		Set("unit", metric.Unit).
+		Set("date", metric.Date).
+		Set("notes", metric.Notes).
+		Where(sq.Eq{"id": metricID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// DeleteMetric will delete a metric
+func DeleteMetric(db *sql.DB, metricID string, userID int) error {
+	deleteQuery := psql.
+		Delete("metric").
+		Where(sq.Eq{"id": metricID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlDeleteQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// selectMetrics builds the query for a user's metrics
+func selectMetrics(userID int) sq.SelectBuilder {
+	return psql.
+		Select("id, type, name, value, unit, date, notes").
+		From("metric").
+		Where(sq.Eq{"user_id": userID})
+}
+
+// scanMetric reads a row produced by selectMetrics
+func scanMetric(rows *sql.Rows) (data.Metric, error) {
+	var metric data.Metric
+
+	err := rows.Scan(&metric.ID, &metric.Type, &metric.Name, &metric.Value, &metric.Unit, &metric.Date, &metric.Notes)
+	return metric, err
+}
+
+func processMetricFilters(baseQuery sq.SelectBuilder, filters *data.MetricFilter) sq.SelectBuilder {
+	if filters.Type != "" {
+		baseQuery = baseQuery.Where(sq.Eq{"type": filters.Type})
+	}
+
+	if filters.Name != "" {
+		baseQuery = baseQuery.Where(sq.Expr("LOWER(name) = LOWER(?)", filters.Name))
+	}
+
+	if !filters.StartDate.IsZero() {
+		baseQuery = baseQuery.Where("date >= ?", filters.StartDate.Unix())
+	}
+
+	if !filters.EndDate.IsZero() {
+		baseQuery = baseQuery.Where("date <= ?", filters.EndDate.Unix())
+	}
+
+	return baseQuery
+}
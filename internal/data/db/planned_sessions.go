@@ -0,0 +1,283 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// CreatePlannedSession will plan a session, linking it to an Activity already logged for that WOD on that day (in
+// the user's time zone)
+func CreatePlannedSession(db *sql.DB, session data.PlannedSessionInput, userID int) (int64, error) {
+	if err := checkWODVisible(db, *session.WODID, userID); err != nil {
+		return 0, err
+	}
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return 0, err
+	}
+	day := data.StartOfDay(session.PlannedDate, loc)
+
+	insertQuery := psql.
+		Insert("planned_session").
+		Columns("user_id, wod_id, planned_date, time_zone, target_score").
+		Values(userID, session.WODID, day, loc.String(), session.TargetScore).
+		Suffix("RETURNING \"id\"")
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	var plannedID int64
+	err = db.QueryRow(sqlInsertQuery, args...).Scan(&plannedID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := linkActivity(db, plannedID, userID); err != nil {
+		return plannedID, err
+	}
+
+	return plannedID, nil
+}
+
+// GetPlannedSessions will get and return planned sessions
+func GetPlannedSessions(db *sql.DB, filters *data.PlannedSessionFilter, userID int) ([]data.PlannedSession, error) {
+	var dbSessions = []data.PlannedSession{}
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	selectQuery := selectPlannedSessions(userID)
+	selectQuery = processPlannedSessionFilters(selectQuery, filters, loc)
+	selectQuery = selectQuery.OrderBy("planned_date, planned_session.id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		session, err := scanPlannedSession(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		dbSessions = append(dbSessions, session)
+	}
+
+	return dbSessions, nil
+}
+
+// GetPlannedSession will get and return an individual planned session
+func GetPlannedSession(db *sql.DB, plannedID string, userID int) (data.PlannedSession, error) {
+	selectQuery := selectPlannedSessions(userID).
+		Where(sq.Eq{"planned_session.id": plannedID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return data.PlannedSession{}, err
+	}
+
+	defer rows.Close()
+	if !rows.Next() {
+		return data.PlannedSession{}, sql.ErrNoRows
+	}
+
+	return scanPlannedSession(rows)
+}
+
+// UpdatePlannedSession will change a planned session, relinking it to any Activity logged for the new WOD and day
+func UpdatePlannedSession(db *sql.DB, plannedID string, session data.PlannedSessionInput, userID int) error {
+	if err := checkWODVisible(db, *session.WODID, userID); err != nil {
+		return err
+	}
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return err
+	}
+	day := data.StartOfDay(session.PlannedDate, loc)
+
+	updateQuery := psql.
+		Update("planned_session").
+		Set("wod_id", session.WODID).
+		Set("planned_date", day).
+		Set("time_zone", loc.String()).
+		Set("target_score", session.TargetScore).
+		Set("activity_id", nil).
+		Where(sq.Eq{"id": plannedID}).
+		Where(sq.Eq{"user_id": userID}).
+		Suffix("RETURNING \"id\"")
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	var id int64
+	err = db.QueryRow(sqlUpdateQuery, args...).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	return linkActivity(db, id, userID)
+}
+
+// DeletePlannedSession will delete a planned session
+func DeletePlannedSession(db *sql.DB, plannedID string, userID int) error {
+	deleteQuery := psql.
+		Delete("planned_session").
+		Where(sq.Eq{"id": plannedID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlDeleteQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlDeleteQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetAdherence will compare the sessions a user planned with those they completed as of now. Sessions before today
+// (in the user's time zone) without an Activity have been missed, the rest are still upcoming.
+func GetAdherence(db *sql.DB, filters *data.PlannedSessionFilter, userID int, now int64) (data.Adherence, error) {
+	var adherence data.Adherence
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return adherence, err
+	}
+
+	selectQuery := psql.
+		Select("COUNT(*), COUNT(activity_id)").
+		Column("COUNT(*) FILTER (WHERE activity_id IS NULL AND planned_date < ?)", data.StartOfDay(now, loc)).
+		From("planned_session").
+		Where(sq.Eq{"user_id": userID})
+	selectQuery = processPlannedSessionFilters(selectQuery, filters, loc)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	err = db.QueryRow(sqlQuery, args...).
+		Scan(&adherence.Planned, &adherence.Completed, &adherence.Missed)
+	if err != nil {
+		return adherence, err
+	}
+
+	adherence.Upcoming = adherence.Planned - adherence.Completed - adherence.Missed
+	if due := adherence.Completed + adherence.Missed; due > 0 {
+		adherence.Rate = float64(adherence.Completed) / float64(due)
+	}
+
+	return adherence, nil
+}
+
+// plannedDate is the SQL for the calendar date of a unix time in the time zone a session was planned in (sessions
+// planned before time zones were kept were planned in UTC)
+func plannedDate(column string) string {
+	return "CAST(TO_TIMESTAMP(" + column + ") AT TIME ZONE COALESCE(planned_session.time_zone, '" + data.DefaultTimeZone + "') AS DATE)"
+}
+
+// linkPlannedSession will mark the first unlinked session planned for an Activity's WOD and day as completed by it.
+// Dates are compared in the time zone each session was planned in, so they still match if the user moves.
+func linkPlannedSession(db queryer, activityID int64, activity data.ActivityInput, userID int) error {
+	if activity.WODID == nil {
+		return nil
+	}
+
+	plannedQuery := sq.
+		Select("id").
+		From("planned_session").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"wod_id": *activity.WODID}).
+		Where(plannedDate("planned_session.planned_date")+" = "+plannedDate("?"), activity.Date).
+		Where(sq.Eq{"activity_id": nil}).
+		OrderBy("id").
+		Limit(1)
+
+	updateQuery := psql.
+		Update("planned_session").
+		Set("activity_id", activityID).
+		Where(plannedQuery.Prefix("id IN (").Suffix(")"))
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// linkActivity will mark a planned session as completed by an Activity already logged for its WOD that day
+func linkActivity(db queryer, plannedID int64, userID int) error {
+	activityQuery := sq.
+		Select("activity.id").
+		From("activity").
+		Join("planned_session ON planned_session.wod_id = activity.wod_id").
+		Where(sq.Eq{"planned_session.id": plannedID}).
+		Where(sq.Eq{"activity.user_id": userID}).
+		Where(plannedDate("activity.date") + " = " + plannedDate("planned_session.planned_date")).
+		Where("NOT EXISTS (SELECT 1 FROM planned_session linked WHERE linked.activity_id = activity.id)").
+		OrderBy("activity.id").
+		Limit(1)
+
+	updateQuery := psql.
+		Update("planned_session").
+		Set("activity_id", activityQuery.Prefix("(").Suffix(")")).
+		Where(sq.Eq{"id": plannedID}).
+		Where(sq.Eq{"user_id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// selectPlannedSessions builds the query for a user's planned sessions (joined with their WOD)
+func selectPlannedSessions(userID int) sq.SelectBuilder {
+	return psql.
+		Select("planned_session.id, planned_date, target_score, activity_id, "+wodColumns).
+		Column("COALESCE(planned_session.time_zone, ?)", data.DefaultTimeZone).
+		From("planned_session").
+		Join("wod ON wod.id = planned_session.wod_id").
+		Where(sq.Eq{"planned_session.user_id": userID})
+}
+
+// scanPlannedSession reads a row produced by selectPlannedSessions
+func scanPlannedSession(rows *sql.Rows) (data.PlannedSession, error) {
+	var session data.PlannedSession
+	var wod data.WOD
+
+	fields := []interface{}{&session.ID, &session.PlannedDate, &session.TargetScore, &session.ActivityID}
+	fields = append(fields, wodFields(&wod)...)
+	if err := rows.Scan(append(fields, &session.TimeZone)...); err != nil {
+		return session, err
+	}
+
+	session.WODID = &wod.ID
+	session.WOD = &wod
+
+	return session, nil
+}
+
+func processPlannedSessionFilters(baseQuery sq.SelectBuilder, filters *data.PlannedSessionFilter, loc *time.Location) sq.SelectBuilder {
+	if !filters.StartDate.IsZero() {
+		baseQuery = baseQuery.Where("planned_date >= ?", data.StartOfDay(filters.StartDate.Unix(), loc))
+	}
+
+	if !filters.EndDate.IsZero() {
+		baseQuery = baseQuery.Where("planned_date <= ?", filters.EndDate.Unix())
+	}
+
+	if filters.Completed != nil {
+		if *filters.Completed {
+			baseQuery = baseQuery.Where(sq.NotEq{"activity_id": nil})
+		} else {
+			baseQuery = baseQuery.Where(sq.Eq{"activity_id": nil})
+		}
+	}
+
+	return baseQuery
+}
@@ -0,0 +1,341 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// ErrWrongPassword is returned when the current password given doesn't match the user's
+var ErrWrongPassword = errors.New("wrong password")
+
+// ErrEmailTaken is returned when another user already has the email address given
+var ErrEmailTaken = errors.New("email address taken")
+
+// GetProfile will get and return the user's profile
+func GetProfile(db *sql.DB, userID int) (data.Profile, error) {
+	var profile data.Profile
+
+	selectQuery := psql.
+		Select("id, username, role, email, COALESCE(email_verified, false), display_name, avatar").
+		Column("COALESCE(time_zone, ?)", data.DefaultTimeZone).
+		Column("COALESCE(units, ?)", units.Metric).
+		Column("birth_year, division").
+		From("\"user\"").
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	err := db.QueryRow(sqlQuery, args...).Scan(&profile.ID, &profile.Username, &profile.Role, &profile.Email, &profile.EmailVerified, &profile.DisplayName,
+		&profile.Avatar, &profile.TimeZone, &profile.Units, &profile.BirthYear, &profile.Division)
+	return profile, err
+}
+
+// GetLocation will get the time zone the user picked (UTC if they haven't), which their days and weeks are in
+func GetLocation(db queryer, userID int) (*time.Location, error) {
+	selectQuery := psql.
+		Select("COALESCE(time_zone, ?)", data.DefaultTimeZone).
+		From("\"user\"").
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var timeZone string
+	if err := db.QueryRow(sqlQuery, args...).Scan(&timeZone); err != nil {
+		return nil, err
+	}
+
+	return data.Location(timeZone), nil
+}
+
+// UpdateProfile will change the parts of the user's profile given, returning whether the email address changed
+func UpdateProfile(db *sql.DB, profile data.ProfileInput, userID int) (bool, error) {
+	updates := map[string]interface{}{}
+	emailChanged := false
+
+	if profile.Email != nil {
+		current, err := GetProfile(db, userID)
+		if err != nil {
+			return false, err
+		}
+
+		if current.Email == nil || *current.Email != *profile.Email {
+			if *profile.Email != "" {
+				if _, err := GetUserIDByEmail(db, *profile.Email); err == nil {
+					return false, ErrEmailTaken
+				} else if err != sql.ErrNoRows {
+					return false, err
+				}
+			}
+
+			updates["email"] = emptyToNull(*profile.Email)
+			updates["email_verified"] = false
+			emailChanged = *profile.Email != ""
+		}
+	}
+
+	if profile.DisplayName != nil {
+		updates["display_name"] = emptyToNull(*profile.DisplayName)
+	}
+	if profile.Avatar != nil {
+		updates["avatar"] = emptyToNull(*profile.Avatar)
+	}
+	if profile.TimeZone != nil {
+		updates["time_zone"] = *profile.TimeZone
+	}
+	if profile.Units != nil {
+		updates["units"] = *profile.Units
+	}
+	if profile.BirthYear != nil {
+		updates["birth_year"] = *profile.BirthYear
+	}
+	if profile.Division != nil {
+		updates["division"] = emptyToNull(*profile.Division)
+	}
+
+	if len(updates) == 0 {
+		return false, nil
+	}
+
+	updateQuery := psql.
+		Update("\"user\"").
+		SetMap(updates).
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return emailChanged, err
+}
+
+// ChangePassword will change the user's password if the current password given is right, ending every other
+// login session (keeping sessionID, the one it was changed from) so anyone else using the old password is logged out
+func ChangePassword(db *sql.DB, change data.PasswordChange, sessionID string, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("password", change.NewPassword).
+		Where(sq.Eq{"id": userID, "password": change.CurrentPassword})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	result, err := tx.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return ErrWrongPassword
+	}
+
+	if err := RevokeOtherSessions(tx, sessionID, userID); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteAccount will delete the user's Activities and everything they've recorded, delete the WODs they created
+// that nobody else uses (leaving the rest without a creator) and anonymise the user so they can't log in again.
+// Their programs, groups and the Gyms nobody else is in are deleted too, but the account can't be deleted while
+// they're the only owner of a Gym with other members (ErrLastGymOwner). It returns the pictures of the deleted WODs
+// (by WOD ID), so they can be deleted too.
+func DeleteAccount(db *sql.DB, userID int) (map[int]data.WODPicture, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	ownActivities := sq.Select("id").From("activity").Where(sq.Eq{"user_id": userID})
+	unusedWODs := sq.
+		Select("id").
+		From("wod").
+		Where(sq.Eq{"created_by": userID}).
+		Where("NOT EXISTS (SELECT 1 FROM activity WHERE activity.wod_id = wod.id AND activity.user_id <> ?)", userID).
+		Where("NOT EXISTS (SELECT 1 FROM program_workout WHERE program_workout.wod_id = wod.id)").
+		Where("NOT EXISTS (SELECT 1 FROM planned_session WHERE planned_session.wod_id = wod.id AND planned_session.user_id <> ?)", userID).
+		Where("NOT EXISTS (SELECT 1 FROM goal WHERE goal.wod_id = wod.id AND goal.user_id <> ?)", userID)
+
+	_, password, err := data.NewToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Programs are deleted first so the WODs in them that nobody else uses are deleted with the rest
+	if err := deleteCoaching(tx, userID); err != nil {
+		return nil, err
+	}
+
+	pictures, err := getWODPictures(tx, unusedWODs)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := []sq.Sqlizer{
+		psql.Delete("comment").Where(sq.Or{sq.Eq{"user_id": userID}, inSubquery("activity_id", ownActivities)}),
+		psql.Delete("reaction").Where(sq.Or{sq.Eq{"user_id": userID}, inSubquery("activity_id", ownActivities)}),
+		psql.Delete("activity_heart_rate").Where(inSubquery("activity_id", ownActivities)),
+		psql.Delete("lift").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("metric").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("goal").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("planned_session").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("calendar_token").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("user_achievement").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("program_assignment").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("athlete_group_member").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("gym_member").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("wod_share_grant").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("wod_share").Where(sq.Eq{"created_by": userID}),
+		psql.Delete("follow").Where(sq.Or{sq.Eq{"follower_id": userID}, sq.Eq{"followee_id": userID}}),
+		psql.Delete("block").Where(sq.Or{sq.Eq{"blocker_id": userID}, sq.Eq{"blocked_id": userID}}),
+		psql.Delete("user_token").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("api_key").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("user_identity").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("oidc_login").Where(sq.Eq{"link_user_id": userID}),
+		psql.Delete("activity").Where(sq.Eq{"user_id": userID}),
+		psql.Delete("comment").Where(inSubquery("wod_id", unusedWODs)),
+		psql.Delete("reaction").Where(inSubquery("wod_id", unusedWODs)),
+		psql.Delete("wod_scaling").Where(inSubquery("wod_id", unusedWODs)),
+		psql.Delete("wod_share").Where(inSubquery("wod_id", unusedWODs)),
+		psql.Delete("wod_share_grant").Where(inSubquery("wod_id", unusedWODs)),
+		psql.Delete("wod").Where(inSubquery("id", unusedWODs)),
+		psql.Update("wod").Set("created_by", nil).Where(sq.Eq{"created_by": userID}),
+		psql.Update("\"user\"").SetMap(map[string]interface{}{
+			"username":       fmt.Sprintf("deleted-%d", userID),
+			"password":       password,
+			"email":          nil,
+			"email_verified": false,
+			"display_name":   nil,
+			"avatar":         nil,
+			"time_zone":      nil,
+			"birth_year":     nil,
+			"division":       nil,
+			"private":        false,
+		}).Where(sq.Eq{"id": userID}),
+	}
+
+	for _, statement := range statements {
+		sqlQuery, args, _ := statement.ToSql()
+		if _, err := tx.Exec(sqlQuery, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := RevokeAllSessions(tx, userID); err != nil {
+		return nil, err
+	}
+
+	return pictures, tx.Commit()
+}
+
+// deleteCoaching deletes the user's programs, groups and the Gyms nobody else is in, leaving the Gyms others are in
+// without a creator (returning ErrLastGymOwner if they're the only owner of one)
+func deleteCoaching(db queryer, userID int) error {
+	selectQuery := psql.
+		Select().
+		Column("EXISTS (SELECT 1 FROM gym_member own WHERE own.user_id = ? AND own.role = ? "+
+			"AND NOT EXISTS (SELECT 1 FROM gym_member other WHERE other.gym_id = own.gym_id AND other.user_id <> ? AND other.role = ?) "+
+			"AND EXISTS (SELECT 1 FROM gym_member other WHERE other.gym_id = own.gym_id AND other.user_id <> ?))",
+			userID, data.GymRoleOwner, userID, data.GymRoleOwner, userID)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var lastOwner bool
+	if err := db.QueryRow(sqlQuery, args...).Scan(&lastOwner); err != nil {
+		return err
+	} else if lastOwner {
+		return ErrLastGymOwner
+	}
+
+	// The Gyms nobody else is in are found up front, as deleting their members changes who's in them
+	emptyQuery := psql.
+		Select("COALESCE(ARRAY_AGG(gym_id), '{}')").
+		From("gym_member").
+		Where(sq.Eq{"user_id": userID}).
+		Where("NOT EXISTS (SELECT 1 FROM gym_member other WHERE other.gym_id = gym_member.gym_id AND other.user_id <> ?)", userID)
+	sqlEmptyQuery, emptyArgs, _ := emptyQuery.ToSql()
+
+	var emptyGyms pq.Int64Array
+	if err := db.QueryRow(sqlEmptyQuery, emptyArgs...).Scan(&emptyGyms); err != nil {
+		return err
+	}
+
+	ownGroups := sq.Select("id").From("athlete_group").Where(sq.Eq{"coach_id": userID})
+	ownPrograms := sq.Select("id").From("program").Where(sq.Eq{"created_by": userID})
+
+	statements := []sq.Sqlizer{
+		psql.Delete("program_assignment").Where(sq.Or{inSubquery("group_id", ownGroups), inSubquery("program_id", ownPrograms)}),
+		psql.Delete("program_workout").Where(inSubquery("program_id", ownPrograms)),
+		psql.Delete("program").Where(sq.Eq{"created_by": userID}),
+		psql.Delete("athlete_group_member").Where(inSubquery("group_id", ownGroups)),
+		psql.Delete("athlete_group").Where(sq.Eq{"coach_id": userID}),
+		psql.Update("wod").
+			Set("gym_id", nil).
+			Set("visibility", sq.Expr("CASE WHEN visibility = ? THEN ? ELSE visibility END", data.VisibilityGym, data.VisibilityPrivate)).
+			Where("gym_id = ANY(?)", emptyGyms),
+		psql.Delete("gym_member").Where("gym_id = ANY(?)", emptyGyms),
+		psql.Delete("gym").Where("id = ANY(?)", emptyGyms),
+		psql.Update("gym").Set("created_by", nil).Where(sq.Eq{"created_by": userID}),
+	}
+
+	for _, statement := range statements {
+		sqlQuery, args, _ := statement.ToSql()
+		if _, err := db.Exec(sqlQuery, args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getWODPictures returns the pictures of the WODs with IDs from wodIDs (by WOD ID)
+func getWODPictures(db queryer, wodIDs sq.SelectBuilder) (map[int]data.WODPicture, error) {
+	selectQuery := psql.
+		Select("id, picture, thumbnail").
+		From("wod").
+		Where(inSubquery("id", wodIDs))
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	pictures := map[int]data.WODPicture{}
+	defer rows.Close()
+	for rows.Next() {
+		var wodID int
+		var wodPicture data.WODPicture
+		if err := rows.Scan(&wodID, &wodPicture.Picture, &wodPicture.Thumbnail); err != nil {
+			return nil, err
+		}
+
+		pictures[wodID] = wodPicture
+	}
+
+	return pictures, rows.Err()
+}
+
+// inSubquery matches rows where column is one of those selected by subQuery
+func inSubquery(column string, subQuery sq.SelectBuilder) sq.Sqlizer {
+	return subQuery.Prefix(column + " IN (").Suffix(")")
+}
+
+// emptyToNull stores an empty string as NULL
+func emptyToNull(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
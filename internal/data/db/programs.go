@@ -0,0 +1,385 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// scheduledDays is how many days after an assignment starts a program workout falls
+const scheduledDays = "(program_workout.week - 1) * 7 + program_workout.day - 1"
+
+// scheduledDate is when a program workout falls for an assignment and scheduledEnd is when that day ends. Days are
+// counted in the assignment's time zone so workouts stay at midnight when the clocks change.
+var (
+	scheduledDate = assignmentDay(scheduledDays)
+	scheduledEnd  = assignmentDay(scheduledDays + " + 1")
+)
+
+// assignmentDay is the SQL for the unix time of midnight the given number of days after an assignment starts
+func assignmentDay(days string) string {
+	timeZone := "COALESCE(program_assignment.time_zone, '" + data.DefaultTimeZone + "')"
+	return "CAST(EXTRACT(EPOCH FROM (TO_TIMESTAMP(program_assignment.start_date) AT TIME ZONE " + timeZone +
+		" + (" + days + ") * INTERVAL '1 day') AT TIME ZONE " + timeZone + ") AS BIGINT)"
+}
+
+// CreateProgram will create a program and a WOD for every workout in it, returning the program's ID and the WODs'
+// (in the order they're given)
+func CreateProgram(db *sql.DB, program data.ProgramInput, userID int) (int, []int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	programQuery := psql.
+		Insert("program").
+		Columns("name, description, created_by").
+		Values(program.Name, program.Description, userID).
+		Suffix("RETURNING \"id\"")
+	sqlProgramQuery, args, _ := programQuery.ToSql()
+
+	var programID int
+	var wodIDs []int
+	err = tx.QueryRow(sqlProgramQuery, args...).Scan(&programID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, week := range program.Weeks {
+		for _, day := range week.Days {
+			for _, wod := range day.WODs {
+				wodID, err := insertWOD(tx, wod, userID)
+				if err != nil {
+					return 0, nil, err
+				}
+				wodIDs = append(wodIDs, wodID)
+
+				workoutQuery := psql.
+					Insert("program_workout").
+					Columns("program_id, week, day, wod_id").
+					Values(programID, week.Week, day.Day, wodID)
+				sqlWorkoutQuery, args, _ := workoutQuery.ToSql()
+
+				if _, err := tx.Exec(sqlWorkoutQuery, args...); err != nil {
+					return 0, nil, err
+				}
+			}
+		}
+	}
+
+	return programID, wodIDs, tx.Commit()
+}
+
+// GetProgram will get and return a program (with its workouts) created by or assigned to the user
+func GetProgram(db *sql.DB, programID string, userID int) (data.Program, error) {
+	var dbProgram = data.Program{}
+
+	programQuery := psql.
+		Select("id, name, description, created_by").
+		From("program").
+		Where(sq.Eq{"id": programID}).
+		Where(sq.Or{
+			sq.Eq{"created_by": userID},
+			assignedPrograms(userID).Prefix("id IN (").Suffix(")"),
+		})
+	sqlProgramQuery, args, _ := programQuery.ToSql()
+
+	err := db.QueryRow(sqlProgramQuery, args...).
+		Scan(&dbProgram.ID, &dbProgram.Name, &dbProgram.Description, &dbProgram.CreatedBy)
+	if err != nil {
+		return dbProgram, err
+	}
+
+	workoutQuery := psql.
+		Select("week, day, " + wodColumns).
+		From("program_workout").
+		Join("wod ON wod.id = program_workout.wod_id").
+		Where(sq.Eq{"program_id": dbProgram.ID}).
+		OrderBy("week, day, wod.id")
+	sqlWorkoutQuery, args, _ := workoutQuery.ToSql()
+
+	rows, err := db.Query(sqlWorkoutQuery, args...)
+	if err != nil {
+		return dbProgram, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var workout data.ProgramWorkout
+		wod := &workout.WOD
+
+		fields := []interface{}{&workout.Week, &workout.Day}
+		if err := rows.Scan(append(fields, wodFields(wod)...)...); err != nil {
+			return dbProgram, err
+		}
+
+		dbProgram.Workouts = append(dbProgram.Workouts, workout)
+	}
+
+	return dbProgram, nil
+}
+
+// GetPrograms will get and return the programs created by or assigned to the user
+func GetPrograms(db *sql.DB, userID int) ([]data.Program, error) {
+	var dbPrograms = []data.Program{}
+
+	selectQuery := psql.
+		Select("id, name, description, created_by").
+		From("program").
+		Where(sq.Or{
+			sq.Eq{"created_by": userID},
+			assignedPrograms(userID).Prefix("id IN (").Suffix(")"),
+		}).
+		OrderBy("id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var program data.Program
+
+		if err := rows.Scan(&program.ID, &program.Name, &program.Description, &program.CreatedBy); err != nil {
+			return nil, err
+		}
+
+		dbPrograms = append(dbPrograms, program)
+	}
+
+	return dbPrograms, nil
+}
+
+// AssignProgram will assign a program created by the user to athletes and groups they coach (returning
+// ErrForbidden if any of them aren't)
+func AssignProgram(db *sql.DB, programID string, assignment data.ProgramAssignmentInput, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ownerQuery := psql.
+		Select("id").
+		From("program").
+		Where(sq.Eq{"id": programID}).
+		Where(sq.Eq{"created_by": userID})
+	sqlOwnerQuery, args, _ := ownerQuery.ToSql()
+
+	var id int
+	if err := tx.QueryRow(sqlOwnerQuery, args...).Scan(&id); err != nil {
+		return err
+	}
+
+	if coached, err := coachesAll(tx, assignment, userID); err != nil {
+		return err
+	} else if !coached {
+		return ErrForbidden
+	}
+
+	// Programs start at midnight in the coach's time zone, and their days are counted in it
+	loc, err := GetLocation(tx, userID)
+	if err != nil {
+		return err
+	}
+
+	startDate := data.StartOfDay(assignment.StartDate, loc)
+	insertQuery := psql.
+		Insert("program_assignment").
+		Columns("program_id, user_id, group_id, start_date, time_zone")
+	for _, athleteID := range assignment.UserIDs {
+		insertQuery = insertQuery.Values(id, athleteID, nil, startDate, loc.String())
+	}
+	for _, groupID := range assignment.GroupIDs {
+		insertQuery = insertQuery.Values(id, nil, groupID, startDate, loc.String())
+	}
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	if _, err := tx.Exec(sqlInsertQuery, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// coachesAll checks the user coaches every group and athlete in assignment: the groups have to be theirs, and the
+// athletes have to be in one of their groups or a gym they own or coach at
+func coachesAll(db queryer, assignment data.ProgramAssignmentInput, userID int) (bool, error) {
+	groupIDs, athleteIDs := pq.Array(assignment.GroupIDs), pq.Array(assignment.UserIDs)
+
+	groupsQuery := sq.
+		Select("COUNT(*)").
+		From("athlete_group").
+		Where("id = ANY(?)", groupIDs).
+		Where(sq.Eq{"coach_id": userID})
+
+	athletesQuery := sq.
+		Select("COUNT(*)").
+		From("\"user\"").
+		Where("\"user\".id = ANY(?)", athleteIDs).
+		Where(sq.Or{
+			sq.Expr("\"user\".id IN (SELECT athlete_group_member.user_id FROM athlete_group_member "+
+				"JOIN athlete_group ON athlete_group.id = athlete_group_member.group_id WHERE athlete_group.coach_id = ?)", userID),
+			sq.Expr("\"user\".id IN (SELECT member.user_id FROM gym_member member "+
+				"JOIN gym_member coach ON coach.gym_id = member.gym_id WHERE coach.user_id = ? AND coach.role IN (?, ?))",
+				userID, data.GymRoleOwner, data.GymRoleCoach),
+		})
+
+	selectQuery := psql.
+		Select().
+		Column(groupsQuery.Prefix("(").Suffix(")")).
+		Column(athletesQuery.Prefix("(").Suffix(")")).
+		Column("(SELECT COUNT(DISTINCT id) FROM UNNEST(?::int[]) id)", groupIDs).
+		Column("(SELECT COUNT(DISTINCT id) FROM UNNEST(?::int[]) id)", athleteIDs)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var groups, athletes, wantGroups, wantAthletes int
+	if err := db.QueryRow(sqlQuery, args...).Scan(&groups, &athletes, &wantGroups, &wantAthletes); err != nil {
+		return false, err
+	}
+
+	return groups == wantGroups && athletes == wantAthletes, nil
+}
+
+// GetAssignedWorkouts will get and return the program workouts scheduled for the user between from and to
+func GetAssignedWorkouts(db *sql.DB, userID int, from int64, to int64) ([]data.AssignedWorkout, error) {
+	var dbWorkouts = []data.AssignedWorkout{}
+
+	completedQuery := sq.
+		Select("activity.id").
+		From("activity").
+		Where("activity.user_id = ?", userID).
+		Where("activity.wod_id = wod.id").
+		Where("activity.date >= " + scheduledDate).
+		Where("activity.date < " + scheduledEnd).
+		OrderBy("activity.id").
+		Limit(1)
+
+	selectQuery := psql.
+		Select("program.id, program.name, week, day").
+		Column(scheduledDate).
+		Column(wodColumns).
+		Column(completedQuery.Prefix("(").Suffix(")")).
+		From("program_assignment").
+		Join("program ON program.id = program_assignment.program_id").
+		Join("program_workout ON program_workout.program_id = program.id").
+		Join("wod ON wod.id = program_workout.wod_id").
+		Where(assignedTo(userID)).
+		Where(scheduledDate+" >= ?", from).
+		Where(scheduledDate+" <= ?", to).
+		OrderBy(scheduledDate, "program.id", "wod.id")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var workout data.AssignedWorkout
+		wod := &workout.WOD
+
+		fields := []interface{}{&workout.ProgramID, &workout.ProgramName, &workout.Week, &workout.Day, &workout.Date}
+		fields = append(fields, wodFields(wod)...)
+		if err := rows.Scan(append(fields, &workout.ActivityID)...); err != nil {
+			return nil, err
+		}
+
+		dbWorkouts = append(dbWorkouts, workout)
+	}
+
+	return dbWorkouts, nil
+}
+
+// GetProgramProgress will get and return how far each athlete assigned a program has got through it as of now
+// (workouts up to today, in the user's time zone, are due). The program's creator sees every athlete, anyone else
+// only sees themselves.
+func GetProgramProgress(db *sql.DB, programID string, userID int, now int64) ([]data.ProgramProgress, error) {
+	var dbProgress = []data.ProgramProgress{}
+
+	loc, err := GetLocation(db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	athletesQuery := sq.
+		Select("program_assignment.user_id AS athlete_id, program_assignment.start_date, program_assignment.time_zone, program_assignment.program_id").
+		From("program_assignment").
+		Where(sq.NotEq{"program_assignment.user_id": nil}).
+		Suffix("UNION SELECT athlete_group_member.user_id, program_assignment.start_date, program_assignment.time_zone, program_assignment.program_id " +
+			"FROM program_assignment JOIN athlete_group_member ON athlete_group_member.group_id = program_assignment.group_id")
+
+	completedQuery := sq.
+		Select("1").
+		From("activity").
+		Where("activity.user_id = program_assignment.athlete_id").
+		Where("activity.wod_id = program_workout.wod_id").
+		Where("activity.date >= " + scheduledDate).
+		Where("activity.date < " + scheduledEnd)
+
+	selectQuery := psql.
+		Select("program_assignment.athlete_id, \"user\".username, COUNT(*)").
+		Column("COUNT(*) FILTER (WHERE "+scheduledDate+" <= ?)", data.StartOfDay(now, loc)).
+		Column(completedQuery.Prefix("COUNT(*) FILTER (WHERE EXISTS (").Suffix("))")).
+		FromSelect(athletesQuery, "program_assignment").
+		Join("program ON program.id = program_assignment.program_id").
+		Join("program_workout ON program_workout.program_id = program.id").
+		Join("\"user\" ON \"user\".id = program_assignment.athlete_id").
+		Where(sq.Eq{"program.id": programID}).
+		Where(sq.Or{
+			sq.Eq{"program.created_by": userID},
+			sq.Eq{"program_assignment.athlete_id": userID},
+		}).
+		GroupBy("program_assignment.athlete_id", "\"user\".username").
+		OrderBy("\"user\".username")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var progress data.ProgramProgress
+
+		if err := rows.Scan(&progress.UserID, &progress.Username, &progress.Workouts, &progress.Due, &progress.Completed); err != nil {
+			return nil, err
+		}
+
+		if progress.Due > 0 {
+			progress.Rate = float64(progress.Completed) / float64(progress.Due)
+		}
+
+		dbProgress = append(dbProgress, progress)
+	}
+
+	return dbProgress, nil
+}
+
+// assignedTo matches program assignments made to the user directly or to one of their groups
+func assignedTo(userID int) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{"program_assignment.user_id": userID},
+		sq.Select("group_id").
+			From("athlete_group_member").
+			Where(sq.Eq{"user_id": userID}).
+			Prefix("program_assignment.group_id IN (").
+			Suffix(")"),
+	}
+}
+
+// assignedPrograms selects the IDs of programs assigned to the user
+func assignedPrograms(userID int) sq.SelectBuilder {
+	return sq.
+		Select("program_id").
+		From("program_assignment").
+		Where(assignedTo(userID))
+}
@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// ErrRefreshTokenReused is returned when a refresh token that has already been swapped is used again, which
+// means it has leaked; the whole session is revoked when it happens
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// CreateSession will start a login session for the user, returning its ID and its first refresh token
+func CreateSession(db *sql.DB, userID int) (string, string, error) {
+	sessionID, _, err := data.NewToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback()
+
+	insertQuery := psql.
+		Insert("session").
+		Columns("id, user_id, created_at").
+		Values(sessionID, userID, time.Now().Unix())
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	if _, err := tx.Exec(sqlQuery, args...); err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := insertRefreshToken(tx, sessionID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return sessionID, refreshToken, tx.Commit()
+}
+
+// RefreshSession will swap a refresh token for a new one, returning the user and session it belongs to. Unknown,
+// expired and revoked tokens give sql.ErrNoRows, and a token used twice revokes its session.
+func RefreshSession(db *sql.DB, refreshToken string) (data.User, string, error) {
+	var user data.User
+
+	tx, err := db.Begin()
+	if err != nil {
+		return user, "", err
+	}
+	defer tx.Rollback()
+
+	selectQuery := psql.
+		Select("refresh_token.used_at IS NOT NULL, refresh_token.expires_at, \"user\".id, \"user\".username, \"user\".role, session.id").
+		From("refresh_token").
+		Join("session ON session.id = refresh_token.session_id").
+		Join("\"user\" ON \"user\".id = session.user_id").
+		Where(sq.Eq{"refresh_token.token_hash": data.HashToken(refreshToken), "session.revoked_at": nil}).
+		Suffix("FOR UPDATE OF refresh_token, session")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var used bool
+	var expiresAt int64
+	err = tx.QueryRow(sqlQuery, args...).Scan(&used, &expiresAt, &user.ID, &user.Username, &user.Role, &user.Session)
+	if err != nil {
+		return user, "", err
+	}
+
+	if used {
+		if err := revokeSessions(tx, sq.Eq{"id": user.Session}); err != nil {
+			return user, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return user, "", err
+		}
+		return user, "", ErrRefreshTokenReused
+	} else if expiresAt <= time.Now().Unix() {
+		return user, "", sql.ErrNoRows
+	}
+
+	updateQuery := psql.
+		Update("refresh_token").
+		Set("used_at", time.Now().Unix()).
+		Where(sq.Eq{"token_hash": data.HashToken(refreshToken)})
+	sqlUpdateQuery, updateArgs, _ := updateQuery.ToSql()
+
+	if _, err := tx.Exec(sqlUpdateQuery, updateArgs...); err != nil {
+		return user, "", err
+	}
+
+	newRefreshToken, err := insertRefreshToken(tx, user.Session)
+	if err != nil {
+		return user, "", err
+	}
+
+	return user, newRefreshToken, tx.Commit()
+}
+
+// RevokeSession will end one of the user's login sessions and deny the access token with ID tokenID until it
+// expires
+func RevokeSession(db *sql.DB, sessionID string, tokenID string, expiresAt int64, userID int) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeSessions(tx, sq.Eq{"id": sessionID, "user_id": userID}); err != nil {
+		return err
+	}
+
+	if err := denyToken(tx, tokenID, expiresAt); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeAllSessions will end every one of the user's login sessions
+func RevokeAllSessions(db queryer, userID int) error {
+	return revokeSessions(db, sq.Eq{"user_id": userID})
+}
+
+// RevokeOtherSessions will end every one of the user's login sessions except sessionID (all of them if it's empty)
+func RevokeOtherSessions(db queryer, sessionID string, userID int) error {
+	return revokeSessions(db, sq.And{sq.Eq{"user_id": userID}, sq.NotEq{"id": sessionID}})
+}
+
+// TokenRevoked will return whether an access token has been denied or its session ended
+func TokenRevoked(db *sql.DB, tokenID string, sessionID string, userID int) (bool, error) {
+	selectQuery := psql.
+		Select().
+		Column("EXISTS (SELECT 1 FROM revoked_token WHERE jti = ?)", tokenID).
+		Column("NOT EXISTS (SELECT 1 FROM session WHERE id = ? AND user_id = ? AND revoked_at IS NULL)", sessionID, userID)
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var denied, ended bool
+	if err := db.QueryRow(sqlQuery, args...).Scan(&denied, &ended); err != nil {
+		return false, err
+	}
+
+	return denied || ended, nil
+}
+
+// insertRefreshToken adds a new refresh token to a session, returning it (only its hash is stored)
+func insertRefreshToken(db queryer, sessionID string) (string, error) {
+	token, hash, err := data.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	insertQuery := psql.
+		Insert("refresh_token").
+		Columns("token_hash, session_id, created_at, expires_at").
+		Values(hash, sessionID, now.Unix(), now.Add(data.RefreshTokenTTL).Unix())
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	_, err = db.Exec(sqlQuery, args...)
+	return token, err
+}
+
+// revokeSessions ends the sessions matching where
+func revokeSessions(db queryer, where sq.Sqlizer) error {
+	updateQuery := psql.
+		Update("session").
+		Set("revoked_at", time.Now().Unix()).
+		Where(where).
+		Where(sq.Eq{"revoked_at": nil})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return err
+}
+
+// denyToken adds an access token to the denylist until it expires (clearing out those that already have)
+func denyToken(db queryer, tokenID string, expiresAt int64) error {
+	deleteQuery := psql.
+		Delete("revoked_token").
+		Where(sq.LtOrEq{"expires_at": time.Now().Unix()})
+	sqlDeleteQuery, deleteArgs, _ := deleteQuery.ToSql()
+
+	if _, err := db.Exec(sqlDeleteQuery, deleteArgs...); err != nil {
+		return err
+	}
+
+	insertQuery := psql.
+		Insert("revoked_token").
+		Columns("jti, expires_at").
+		Values(tokenID, expiresAt).
+		Suffix("ON CONFLICT DO NOTHING")
+	sqlInsertQuery, insertArgs, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlInsertQuery, insertArgs...)
+	return err
+}
@@ -0,0 +1,353 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/lib/pq"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// GetFeed will get and return a page of recent Activities by the user, people they follow and people they share a gym with
+func GetFeed(db *sql.DB, filters *data.PageFilter, userID int) (data.FeedPage, error) {
+	page := data.FeedPage{Items: []data.FeedItem{}}
+
+	selectQuery := psql.
+		Select("activity.id, activity.user_id, \"user\".username, activity.date, activity.time_taken, activity.meps, activity.exertion, activity.notes, activity.tier, activity.substitutions, " + wodColumns).
+		Column("(SELECT COUNT(*) FROM comment WHERE comment.activity_id = activity.id)").
+		From("activity").
+		Join("wod ON wod.id = activity.wod_id").
+		Join("\"user\" ON \"user\".id = activity.user_id").
+		Where(feedAuthors(userID)).
+		Where(visibleWODs(userID)).
+		OrderBy("activity.date DESC, activity.id DESC").
+		Limit(uint64(*filters.Limit + 1))
+
+	if filters.Cursor != "" {
+		date, id, err := data.DecodeCursor(filters.Cursor)
+		if err != nil {
+			return page, err
+		}
+		selectQuery = selectQuery.Where("(activity.date, activity.id) < (?, ?)", date, id)
+	}
+
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return page, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var item data.FeedItem
+		var wod data.WOD
+
+		fields := []interface{}{&item.ID, &item.UserID, &item.Username, &item.Date, &item.TimeTaken, &item.MEPs, &item.Exertion, &item.Notes, &item.Tier, &item.Substitutions}
+		fields = append(fields, wodFields(&wod)...)
+		if err := rows.Scan(append(fields, &item.Comments)...); err != nil {
+			return page, err
+		}
+
+		item.WOD = &wod
+		item.Reactions = map[string]int{}
+		item.MyReactions = []string{}
+		page.Items = append(page.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > *filters.Limit {
+		page.Items = page.Items[:*filters.Limit]
+		last := page.Items[len(page.Items)-1]
+		next := data.EncodeCursor(last.Date, last.ID)
+		page.Next = &next
+	}
+
+	return page, addFeedReactions(db, page.Items, userID)
+}
+
+// addFeedReactions counts the reactions on each item in the feed (and notes which the user left)
+func addFeedReactions(db *sql.DB, items []data.FeedItem, userID int) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	byActivity := map[int64]*data.FeedItem{}
+	activityIDs := pq.Int64Array{}
+	for i := range items {
+		byActivity[items[i].ID] = &items[i]
+		activityIDs = append(activityIDs, items[i].ID)
+	}
+
+	selectQuery := psql.
+		Select("activity_id, kind, COUNT(*)").
+		Column("BOOL_OR(user_id = ?)", userID).
+		From("reaction").
+		Where("activity_id = ANY(?)", activityIDs).
+		GroupBy("activity_id, kind")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var activityID int64
+		var kind string
+		var count int
+		var mine bool
+
+		if err := rows.Scan(&activityID, &kind, &count, &mine); err != nil {
+			return err
+		}
+
+		item := byActivity[activityID]
+		item.Reactions[kind] = count
+		if mine {
+			item.MyReactions = append(item.MyReactions, kind)
+		}
+	}
+
+	return rows.Err()
+}
+
+// feedAuthors matches Activities by the user, anyone they follow or anyone they share a gym with (unless either
+// has blocked the other)
+func feedAuthors(userID int) sq.Sqlizer {
+	return sq.Or{
+		sq.Eq{"activity.user_id": userID},
+		sq.And{
+			sq.Or{
+				followedBy("activity.user_id", userID),
+				sq.Select("mate.user_id").
+					From("gym_member mate").
+					Join("gym_member mine ON mine.gym_id = mate.gym_id").
+					Where(sq.Eq{"mine.user_id": userID}).
+					Prefix("activity.user_id IN (").
+					Suffix(")"),
+			},
+			notBlocked("activity.user_id", userID),
+		},
+	}
+}
+
+// checkTargetVisible returns who owns the Activity or WOD being commented on or reacted to, or sql.ErrNoRows
+// unless the user can see it (an Activity has to be in their feed as well as its WOD visible to them, and a WOD
+// can't be from someone they've blocked or been blocked by)
+func checkTargetVisible(db queryer, target data.CommentTarget, userID int) (int, error) {
+	var selectQuery sq.SelectBuilder
+
+	if target.ActivityID != nil {
+		selectQuery = psql.
+			Select("activity.user_id").
+			From("activity").
+			Join("wod ON wod.id = activity.wod_id").
+			Where(sq.Eq{"activity.id": *target.ActivityID}).
+			Where(feedAuthors(userID)).
+			Where(visibleWODs(userID))
+	} else {
+		selectQuery = psql.
+			Select("COALESCE(wod.created_by, 0)").
+			From("wod").
+			Where(sq.Eq{"wod.id": *target.WODID}).
+			Where(visibleWODs(userID)).
+			Where(sq.Or{sq.Eq{"wod.created_by": nil}, sq.Eq{"wod.created_by": userID}, notBlocked("wod.created_by", userID)})
+	}
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var ownerID int
+	err := db.QueryRow(sqlQuery, args...).Scan(&ownerID)
+	return ownerID, err
+}
+
+// targetColumn is the column comments and reactions on the target are stored against
+func targetColumn(target data.CommentTarget) sq.Eq {
+	if target.ActivityID != nil {
+		return sq.Eq{"activity_id": *target.ActivityID}
+	}
+	return sq.Eq{"wod_id": *target.WODID}
+}
+
+// CreateComment will add the user's comment to an Activity or WOD, returning its ID and who owns what was commented on
+func CreateComment(db *sql.DB, target data.CommentTarget, comment data.CommentInput, userID int) (int64, int, error) {
+	ownerID, err := checkTargetVisible(db, target, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	insertQuery := psql.
+		Insert("comment").
+		Columns("user_id, activity_id, wod_id, body, created_at").
+		Values(userID, target.ActivityID, target.WODID, comment.Body, time.Now().Unix()).
+		Suffix("RETURNING \"id\"")
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	var commentID int64
+	if err := db.QueryRow(sqlQuery, args...).Scan(&commentID); err != nil {
+		return 0, 0, err
+	}
+
+	return commentID, ownerID, nil
+}
+
+// GetComments will get and return a page of the comments on an Activity or WOD (oldest first, leaving out those by
+// anyone the user has blocked or been blocked by) with its reactions
+func GetComments(db *sql.DB, target data.CommentTarget, filters *data.PageFilter, userID int) (data.CommentPage, error) {
+	page := data.CommentPage{Items: []data.Comment{}, Reactions: map[string]int{}}
+
+	if _, err := checkTargetVisible(db, target, userID); err != nil {
+		return page, err
+	}
+
+	selectQuery := psql.
+		Select("comment.id, comment.user_id, \"user\".username, comment.activity_id, comment.wod_id, comment.body, comment.created_at").
+		From("comment").
+		Join("\"user\" ON \"user\".id = comment.user_id").
+		Where(targetColumn(target)).
+		Where(sq.Or{sq.Eq{"comment.user_id": userID}, notBlocked("comment.user_id", userID)}).
+		OrderBy("comment.created_at, comment.id").
+		Limit(uint64(*filters.Limit + 1))
+
+	if filters.Cursor != "" {
+		createdAt, id, err := data.DecodeCursor(filters.Cursor)
+		if err != nil {
+			return page, err
+		}
+		selectQuery = selectQuery.Where("(comment.created_at, comment.id) > (?, ?)", createdAt, id)
+	}
+
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	rows, err := db.Query(sqlQuery, args...)
+	if err != nil {
+		return page, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var comment data.Comment
+
+		if err := rows.Scan(&comment.ID, &comment.UserID, &comment.Username, &comment.ActivityID, &comment.WODID, &comment.Body, &comment.CreatedAt); err != nil {
+			return page, err
+		}
+
+		page.Items = append(page.Items, comment)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > *filters.Limit {
+		page.Items = page.Items[:*filters.Limit]
+		last := page.Items[len(page.Items)-1]
+		next := data.EncodeCursor(last.CreatedAt, last.ID)
+		page.Next = &next
+	}
+
+	reactionQuery := psql.
+		Select("kind, COUNT(*)").
+		From("reaction").
+		Where(targetColumn(target)).
+		GroupBy("kind")
+	sqlReactionQuery, reactionArgs, _ := reactionQuery.ToSql()
+
+	reactionRows, err := db.Query(sqlReactionQuery, reactionArgs...)
+	if err != nil {
+		return page, err
+	}
+
+	defer reactionRows.Close()
+	for reactionRows.Next() {
+		var kind string
+		var count int
+
+		if err := reactionRows.Scan(&kind, &count); err != nil {
+			return page, err
+		}
+
+		page.Reactions[kind] = count
+	}
+
+	return page, reactionRows.Err()
+}
+
+// DeleteComment will delete a comment the user wrote or that was left on their Activity or WOD
+func DeleteComment(db *sql.DB, commentID string, userID int) error {
+	deleteQuery := psql.
+		Delete("comment").
+		Where(sq.Eq{"id": commentID}).
+		Where(sq.Or{
+			sq.Eq{"user_id": userID},
+			sq.Select("id").
+				From("activity").
+				Where(sq.Eq{"user_id": userID}).
+				Prefix("activity_id IN (").
+				Suffix(")"),
+			sq.Select("id").
+				From("wod").
+				Where(sq.Eq{"created_by": userID}).
+				Prefix("wod_id IN (").
+				Suffix(")"),
+		})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetReaction will leave the user's reaction on an Activity or WOD (leaving it again does nothing)
+func SetReaction(db *sql.DB, target data.CommentTarget, kind string, userID int) error {
+	if _, err := checkTargetVisible(db, target, userID); err != nil {
+		return err
+	}
+
+	insertQuery := psql.
+		Insert("reaction").
+		Columns("user_id, activity_id, wod_id, kind").
+		Values(userID, target.ActivityID, target.WODID, kind).
+		Suffix("ON CONFLICT DO NOTHING")
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return err
+}
+
+// RemoveReaction will take back the user's reaction on an Activity or WOD
+func RemoveReaction(db *sql.DB, target data.CommentTarget, kind string, userID int) error {
+	deleteQuery := psql.
+		Delete("reaction").
+		Where(targetColumn(target)).
+		Where(sq.Eq{"user_id": userID, "kind": kind})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	result, err := db.Exec(sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// GetUnits will return the unit system a user has picked (metric if they haven't)
+func GetUnits(db *sql.DB, userID int) (units.System, error) {
+	selectQuery := psql.
+		Select().
+		Column("COALESCE(units, ?)", units.Metric).
+		From("\"user\"").
+		Where(sq.Eq{"id": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var system units.System
+	err := db.QueryRow(sqlQuery, args...).Scan(&system)
+	return system, err
+}
+
+// UpdateUnits will change the unit system a user sees loads and distances in
+func UpdateUnits(db *sql.DB, system units.System, userID int) error {
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("units", system).
+		Where(sq.Eq{"id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlUpdateQuery, args...)
+	return err
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// GetUserIDByEmail will return the ID of the user with an email address
+func GetUserIDByEmail(db queryer, email string) (int, error) {
+	selectQuery := psql.
+		Select("id").
+		From("\"user\"").
+		Where(sq.Eq{"email": email})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var userID int
+	err := db.QueryRow(sqlQuery, args...).Scan(&userID)
+	return userID, err
+}
+
+// CreateUserToken will create a single-use token for purpose that expires after ttl, returning the token (only its
+// hash is stored). Email is the address the token is sent to.
+func CreateUserToken(db *sql.DB, userID int, purpose string, email string, ttl time.Duration) (string, error) {
+	token, hash, err := data.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+
+	insertQuery := psql.
+		Insert("user_token").
+		Columns("token_hash, user_id, purpose, email, created_at, expires_at").
+		Values(hash, userID, purpose, email, now.Unix(), now.Add(ttl).Unix())
+	sqlQuery, args, _ := insertQuery.ToSql()
+
+	if _, err := db.Exec(sqlQuery, args...); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ResetPassword will change the password of the user a password reset token was sent to, using up the token (and
+// any others they were sent) and logging them out everywhere
+func ResetPassword(db *sql.DB, token string, newPassword string) (int, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	userID, _, err := useUserToken(tx, token, data.TokenPasswordReset)
+	if err != nil {
+		return 0, err
+	}
+
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("password", newPassword).
+		Where(sq.Eq{"id": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	if _, err := tx.Exec(sqlUpdateQuery, args...); err != nil {
+		return 0, err
+	}
+
+	if err := expireUserTokens(tx, userID, data.TokenPasswordReset); err != nil {
+		return 0, err
+	}
+
+	if err := RevokeAllSessions(tx, userID); err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+// VerifyEmail will mark the email address a verification token was sent to as verified, as long as the user
+// still has that address
+func VerifyEmail(db *sql.DB, token string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	userID, email, err := useUserToken(tx, token, data.TokenEmailVerification)
+	if err != nil {
+		return err
+	}
+
+	updateQuery := psql.
+		Update("\"user\"").
+		Set("email_verified", true).
+		Where(sq.Eq{"id": userID, "email": email})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := tx.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	} else if updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return tx.Commit()
+}
+
+// useUserToken marks an unused, unexpired token for purpose as used, returning who it was for and the address it
+// was sent to (or sql.ErrNoRows if there's no such token)
+func useUserToken(db queryer, token string, purpose string) (int, string, error) {
+	now := time.Now().Unix()
+
+	updateQuery := psql.
+		Update("user_token").
+		Set("used_at", now).
+		Where(sq.Eq{"token_hash": data.HashToken(token), "purpose": purpose, "used_at": nil}).
+		Where(sq.Gt{"expires_at": now}).
+		Suffix("RETURNING user_id, email")
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	var userID int
+	var email string
+	err := db.QueryRow(sqlQuery, args...).Scan(&userID, &email)
+	return userID, email, err
+}
+
+// expireUserTokens marks every unused token for purpose the user has as used
+func expireUserTokens(db queryer, userID int, purpose string) error {
+	updateQuery := psql.
+		Update("user_token").
+		Set("used_at", time.Now().Unix()).
+		Where(sq.Eq{"user_id": userID, "purpose": purpose, "used_at": nil})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	_, err := db.Exec(sqlQuery, args...)
+	return err
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+)
+
+// GetWODPicture will get and return the picture of a WOD created by the user (or sql.ErrNoRows if they didn't
+// create it)
+func GetWODPicture(db *sql.DB, wodID string, userID int) (data.WODPicture, error) {
+	selectQuery := psql.
+		Select("picture, thumbnail").
+		From("wod").
+		Where(sq.Eq{"id": wodID}).
+		Where(sq.Eq{"created_by": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var wodPicture data.WODPicture
+	err := db.QueryRow(sqlQuery, args...).Scan(&wodPicture.Picture, &wodPicture.Thumbnail)
+	return wodPicture, err
+}
+
+// UpdateWODPicture will set the picture (and its thumbnail) of a WOD created by the user
+func UpdateWODPicture(db *sql.DB, wodID string, picture string, thumbnail string, userID int) error {
+	updateQuery := psql.
+		Update("wod").
+		Set("picture", picture).
+		Set("thumbnail", thumbnail).
+		Where(sq.Eq{"id": wodID}).
+		Where(sq.Eq{"created_by": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package db
+
+import (
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// addScaling fills in the scaling options of each WOD and the user's results at each tier (attempts without a tier
+// aren't counted at any)
+func addScaling(db queryer, wods []*data.WOD, userID int) error {
+	if len(wods) == 0 {
+		return nil
+	}
+
+	byID := map[int]*data.WOD{}
+	wodIDs := []int{}
+	for _, wod := range wods {
+		byID[wod.ID] = wod
+		wodIDs = append(wodIDs, wod.ID)
+	}
+
+	scalingQuery := psql.
+		Select("wod_id, tier, load, distance, calories, loads, description").
+		From("wod_scaling").
+		Where(sq.Eq{"wod_id": wodIDs}).
+		OrderBy("wod_id, id")
+	sqlScalingQuery, args, _ := scalingQuery.ToSql()
+
+	rows, err := db.Query(sqlScalingQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var wodID int
+		var option data.ScalingOption
+		var load, distance, calories *float64
+
+		if err := rows.Scan(&wodID, &option.Tier, &load, &distance, &calories, &option.Loads, &option.Description); err != nil {
+			return err
+		}
+
+		if load != nil {
+			option.Load = &units.Measure{Amount: *load, Unit: units.Kilograms}
+		}
+		if distance != nil {
+			option.Distance = &units.Measure{Amount: *distance, Unit: units.Metres}
+		} else if calories != nil {
+			option.Distance = &units.Measure{Amount: *calories, Unit: units.Calories}
+		}
+
+		byID[wodID].Scaling = append(byID[wodID].Scaling, option)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	resultQuery := psql.
+		Select("wod_id, tier, COUNT(*), MIN(time_taken)").
+		From("activity").
+		Where(sq.Eq{"wod_id": wodIDs}).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.NotEq{"tier": nil}).
+		GroupBy("wod_id", "tier").
+		OrderBy("wod_id", "tier")
+	sqlResultQuery, args, _ := resultQuery.ToSql()
+
+	resultRows, err := db.Query(sqlResultQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	defer resultRows.Close()
+	for resultRows.Next() {
+		var wodID int
+		var result data.TierResult
+
+		if err := resultRows.Scan(&wodID, &result.Tier, &result.Attempts, &result.BestTime); err != nil {
+			return err
+		}
+
+		byID[wodID].TierResults = append(byID[wodID].TierResults, result)
+	}
+
+	return resultRows.Err()
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"database/sql"
+	"strconv"
+
+	sq "github.com/Masterminds/squirrel"
+	_ "github.com/heroku/x/hmetrics/onload"
+)
+
+// CreateWODShare will store a share link for a WOD created by the user
+func CreateWODShare(db *sql.DB, wodID string, tokenHash string, userID int) error {
+	id, err := getCreatedWOD(db, wodID, userID)
+	if err != nil {
+		return err
+	}
+
+	insertQuery := psql.
+		Insert("wod_share").
+		Columns("wod_id, token_hash, created_by, created_at").
+		Values(id, tokenHash, userID, sq.Expr("NOW()"))
+	sqlInsertQuery, args, _ := insertQuery.ToSql()
+
+	_, err = db.Exec(sqlInsertQuery, args...)
+	return err
+}
+
+// RevokeWODShares will stop all share links for a WOD created by the user from working. Anyone who has
+// already opened a link keeps access.
+func RevokeWODShares(db *sql.DB, wodID string, userID int) error {
+	id, err := getCreatedWOD(db, wodID, userID)
+	if err != nil {
+		return err
+	}
+
+	updateQuery := psql.
+		Update("wod_share").
+		Set("revoked_at", sq.Expr("NOW()")).
+		Where(sq.Eq{"wod_id": id}).
+		Where(sq.Eq{"revoked_at": nil})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	_, err = db.Exec(sqlUpdateQuery, args...)
+	return err
+}
+
+// RedeemWODShare will give the user read access to the WOD a share link is for and return its ID
+func RedeemWODShare(db *sql.DB, tokenHash string, userID int) (string, error) {
+	selectQuery := psql.
+		Select("wod_id").
+		From("wod_share").
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Eq{"revoked_at": nil})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var wodID int
+	err := db.QueryRow(sqlQuery, args...).Scan(&wodID)
+	if err != nil {
+		return "", err
+	}
+
+	grantQuery := psql.
+		Insert("wod_share_grant").
+		Columns("wod_id, user_id").
+		Values(wodID, userID).
+		Suffix("ON CONFLICT DO NOTHING")
+	sqlGrantQuery, args, _ := grantQuery.ToSql()
+
+	if _, err := db.Exec(sqlGrantQuery, args...); err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(wodID), nil
+}
+
+// UpdateWODVisibility will change who can see a WOD created by the user
+func UpdateWODVisibility(db *sql.DB, wodID string, visibility string, userID int) error {
+	updateQuery := psql.
+		Update("wod").
+		Set("visibility", visibility).
+		Where(sq.Eq{"id": wodID}).
+		Where(sq.Eq{"created_by": userID})
+	sqlUpdateQuery, args, _ := updateQuery.ToSql()
+
+	result, err := db.Exec(sqlUpdateQuery, args...)
+	if err != nil {
+		return err
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// getCreatedWOD checks the WOD was created by the user and returns its ID
+func getCreatedWOD(db queryer, wodID string, userID int) (int, error) {
+	selectQuery := psql.
+		Select("id").
+		From("wod").
+		Where(sq.Eq{"id": wodID}).
+		Where(sq.Eq{"created_by": userID})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	var id int
+	err := db.QueryRow(sqlQuery, args...).Scan(&id)
+	return id, err
+}
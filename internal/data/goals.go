@@ -0,0 +1,197 @@
+package data
+
+import (
+	"math"
+	"time"
+)
+
+// Types of goal
+const (
+	GoalScore     = "score"
+	GoalFrequency = "frequency"
+	GoalVolume    = "volume"
+)
+
+// What volume goals add up
+const (
+	MeasureTime = "time"
+	MeasureMEPs = "meps"
+)
+
+// Periods frequency and volume goals are counted over
+const (
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// Statuses of a goal
+const (
+	GoalOnTrack  = "onTrack"
+	GoalAtRisk   = "atRisk"
+	GoalAchieved = "achieved"
+	GoalMissed   = "missed"
+)
+
+// ValidGoalType checks goalType is one of the known types of goal
+func ValidGoalType(goalType string) bool {
+	switch goalType {
+	case GoalScore, GoalFrequency, GoalVolume:
+		return true
+	}
+	return false
+}
+
+// ValidPeriod checks period is one of the periods goals are counted over
+func ValidPeriod(period string) bool {
+	return period == PeriodWeek || period == PeriodMonth
+}
+
+// ValidMeasure checks measure is one of the things volume goals add up
+func ValidMeasure(measure string) bool {
+	return measure == MeasureTime || measure == MeasureMEPs
+}
+
+// PeriodBounds returns the start and end (exclusive) of the week (from Monday) or month t is in, in loc (the
+// user's time zone)
+func PeriodBounds(period string, t int64, loc *time.Location) (int64, int64) {
+	day := time.Unix(StartOfDay(t, loc), 0).In(loc)
+
+	if period == PeriodMonth {
+		start := time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, loc)
+		return start.Unix(), start.AddDate(0, 1, 0).Unix()
+	}
+
+	daysSinceMonday := (int(day.Weekday()) + 6) % 7
+	start := day.AddDate(0, 0, -daysSinceMonday)
+	return start.Unix(), start.AddDate(0, 0, 7).Unix()
+}
+
+// EvaluationTime is when a goal should be evaluated at: now, or its deadline once that's passed
+func (g *GoalInput) EvaluationTime(now int64) int64 {
+	if g.Deadline != nil && *g.Deadline < now {
+		return *g.Deadline
+	}
+	return now
+}
+
+// Evaluate works out a goal's progress and status from stats at now. Score goals are achieved for good once the
+// time is beaten; frequency and volume goals are evaluated for the current period (or the last, once the
+// deadline has passed), in loc (the user's time zone).
+func (g *Goal) Evaluate(stats GoalStats, now int64, loc *time.Location) {
+	at := g.EvaluationTime(now)
+	ended := at < now
+
+	if g.Type == GoalScore {
+		g.evaluateScore(stats, at, ended)
+	} else {
+		g.evaluatePeriod(stats, at, ended, loc)
+	}
+
+	g.Progress = math.Round(math.Min(math.Max(g.Progress, 0), 100)*10) / 10
+}
+
+func (g *Goal) evaluateScore(stats GoalStats, at int64, ended bool) {
+	g.Current = nil
+	if len(stats.Times) > 0 {
+		best := stats.Times[0]
+		for _, t := range stats.Times {
+			if t < best {
+				best = t
+			}
+		}
+		g.Current = &best
+
+		// Progress is how much of the gap between the first attempt and the target has been closed
+		first := stats.Times[0]
+		switch {
+		case best <= g.Target:
+			g.Progress = 100
+		case first > g.Target:
+			g.Progress = float64(first-best) / float64(first-g.Target) * 100
+		default:
+			g.Progress = 0
+		}
+	} else {
+		g.Progress = 0
+	}
+
+	switch {
+	case g.Progress >= 100:
+		g.Status = GoalAchieved
+	case ended:
+		g.Status = GoalMissed
+	case g.Deadline == nil || *g.Deadline <= g.StartDate:
+		g.Status = GoalOnTrack
+	default:
+		// At risk once less progress has been made than time has passed (with some leeway)
+		elapsed := float64(at-g.StartDate) / float64(*g.Deadline-g.StartDate) * 100
+		if g.Progress+25 >= elapsed {
+			g.Status = GoalOnTrack
+		} else {
+			g.Status = GoalAtRisk
+		}
+	}
+}
+
+func (g *Goal) evaluatePeriod(stats GoalStats, at int64, ended bool, loc *time.Location) {
+	current := stats.Count
+	if g.Type == GoalVolume {
+		current = stats.TimeTaken
+		if g.Measure != nil && *g.Measure == MeasureMEPs {
+			current = stats.MEPs
+		}
+	}
+	g.Current = &current
+
+	if g.Target > 0 {
+		g.Progress = float64(current) / float64(g.Target) * 100
+	}
+
+	period := PeriodWeek
+	if g.Period != nil {
+		period = *g.Period
+	}
+	start, end := PeriodBounds(period, at, loc)
+
+	switch {
+	case g.Progress >= 100:
+		g.Status = GoalAchieved
+	case ended:
+		g.Status = GoalMissed
+	default:
+		// At risk once behind the pace needed to reach the target by the end of the period
+		elapsed := float64(NextDay(StartOfDay(at, loc), loc)-start) / float64(end-start)
+		if float64(current) >= math.Floor(float64(g.Target)*elapsed) {
+			g.Status = GoalOnTrack
+		} else {
+			g.Status = GoalAtRisk
+		}
+	}
+}
+
+// SummariseGoals counts goals by status
+func SummariseGoals(goals []Goal) GoalSummary {
+	summary := GoalSummary{Total: len(goals)}
+
+	var progress float64
+	for _, goal := range goals {
+		progress += goal.Progress
+
+		switch goal.Status {
+		case GoalAchieved:
+			summary.Achieved++
+		case GoalOnTrack:
+			summary.OnTrack++
+		case GoalAtRisk:
+			summary.AtRisk++
+		case GoalMissed:
+			summary.Missed++
+		}
+	}
+
+	if len(goals) > 0 {
+		summary.AverageProgress = math.Round(progress/float64(len(goals))*10) / 10
+	}
+
+	return summary
+}
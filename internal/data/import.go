@@ -0,0 +1,292 @@
+package data
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ImportMapping maps the columns of an uploaded CSV file onto WOD and Activity fields
+type ImportMapping struct {
+	Date          string `json:"date"`
+	TimeTaken     string `json:"timeTaken"`
+	Exercise      string `json:"exercise"`
+	Source        string `json:"source"`
+	Type          string `json:"type"`
+	MEPs          string `json:"meps"`
+	Exertion      string `json:"exertion"`
+	Notes         string `json:"notes"`
+	Tier          string `json:"tier"`
+	Substitutions string `json:"substitutions"`
+	DateFormat    string `json:"dateFormat"`
+	DefaultType   string `json:"defaultType"`
+}
+
+// ImportFormats are the column mappings for exports from other trackers
+var ImportFormats = map[string]ImportMapping{
+	"wodland": {
+		Date:          "date",
+		TimeTaken:     "timeTaken",
+		Exercise:      "wodExercise",
+		Source:        "wodSource",
+		Type:          "wodType",
+		MEPs:          "meps",
+		Exertion:      "exertion",
+		Notes:         "notes",
+		Tier:          "tier",
+		Substitutions: "substitutions",
+	},
+	"sugarwod": {
+		Date:        "date",
+		TimeTaken:   "best_result_raw",
+		Exercise:    "description",
+		Source:      "title",
+		Notes:       "notes",
+		DateFormat:  "01/02/2006",
+		DefaultType: "WOD",
+	},
+	"btwb": {
+		Date:        "Date",
+		TimeTaken:   "Result",
+		Exercise:    "Description",
+		Source:      "Workout",
+		Notes:       "Notes",
+		DateFormat:  "2006-01-02",
+		DefaultType: "WOD",
+	},
+}
+
+// ImportRow is a single parsed row of an import file
+type ImportRow struct {
+	Line     int
+	WOD      WODInput
+	Activity ActivityInput
+}
+
+// ImportRowResult is the outcome of importing a single row
+type ImportRowResult struct {
+	Line       int    `json:"line"`
+	WODID      *int   `json:"wodID,omitempty"`
+	NewWOD     bool   `json:"newWOD"`
+	ActivityID *int64 `json:"activityID,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportReport is returned from the import endpoint
+type ImportReport struct {
+	DryRun            bool              `json:"dryRun"`
+	Committed         bool              `json:"committed"`
+	Rows              int               `json:"rows"`
+	WODsCreated       int               `json:"wodsCreated"`
+	ActivitiesCreated int               `json:"activitiesCreated"`
+	Results           []ImportRowResult `json:"results"`
+}
+
+var importDateFormats = []string{
+	time.RFC3339,
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"01/02/2006",
+}
+
+// ParseImport reads a CSV file using mapping, returning the parsed rows and a result for any row that failed to parse
+func ParseImport(r io.Reader, mapping ImportMapping) ([]ImportRow, []ImportRowResult, error) {
+	if mapping.Date == "" || mapping.TimeTaken == "" || mapping.Exercise == "" {
+		return nil, nil, errors.New("mapping must include date, timeTaken and exercise columns")
+	}
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading header: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for _, required := range []string{mapping.Date, mapping.TimeTaken, mapping.Exercise} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("missing column '%s'", required)
+		}
+	}
+
+	var rows []ImportRow
+	var failures []ImportRowResult
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			failures = append(failures, ImportRowResult{Line: line, Error: err.Error()})
+			continue
+		}
+
+		row, err := parseImportRecord(record, columns, mapping)
+		if err != nil {
+			failures = append(failures, ImportRowResult{Line: line, Error: err.Error()})
+			continue
+		}
+
+		row.Line = line
+		rows = append(rows, row)
+	}
+
+	return rows, failures, nil
+}
+
+func parseImportRecord(record []string, columns map[string]int, mapping ImportMapping) (ImportRow, error) {
+	row := ImportRow{}
+
+	field := func(column string) string {
+		if column == "" {
+			return ""
+		}
+		if i, ok := columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	date, err := parseImportDate(field(mapping.Date), mapping.DateFormat)
+	if err != nil {
+		return row, err
+	}
+	row.Activity.Date = date.Unix()
+	row.WOD.CreationT = date.Unix()
+
+	timeTaken, err := ParseDuration(field(mapping.TimeTaken))
+	if err != nil {
+		return row, err
+	}
+	row.Activity.TimeTaken = timeTaken
+
+	exercise := field(mapping.Exercise)
+	if exercise == "" {
+		return row, errors.New("exercise is empty")
+	}
+	row.WOD.Exercise = &exercise
+
+	if source := field(mapping.Source); source != "" {
+		row.WOD.Source = &source
+	}
+
+	// WODs created from someone's training history are theirs alone
+	row.WOD.Visibility = VisibilityPrivate
+
+	row.WOD.Type = field(mapping.Type)
+	if row.WOD.Type == "" {
+		row.WOD.Type = mapping.DefaultType
+	}
+	if row.WOD.Type == "" {
+		row.WOD.Type = "WOD"
+	}
+
+	if meps := field(mapping.MEPs); meps != "" {
+		value, err := strconv.ParseInt(meps, 10, 64)
+		if err != nil {
+			return row, fmt.Errorf("invalid meps '%s'", meps)
+		}
+		row.Activity.MEPs = &value
+	}
+
+	if exertion := field(mapping.Exertion); exertion != "" {
+		value, err := strconv.ParseInt(exertion, 10, 64)
+		if err != nil {
+			return row, fmt.Errorf("invalid exertion '%s'", exertion)
+		}
+		row.Activity.Exertion = &value
+	}
+
+	if notes := field(mapping.Notes); notes != "" {
+		row.Activity.Notes = &notes
+	}
+
+	if tier := field(mapping.Tier); tier != "" {
+		if !ValidTier(tier) {
+			return row, fmt.Errorf("invalid tier '%s'", tier)
+		}
+		row.Activity.Tier = &tier
+	}
+
+	if substitutions := field(mapping.Substitutions); substitutions != "" {
+		row.Activity.Substitutions = &substitutions
+	}
+
+	return row, nil
+}
+
+func parseImportDate(value string, format string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("date is empty")
+	}
+
+	formats := importDateFormats
+	if format != "" {
+		formats = []string{format}
+	}
+
+	for _, layout := range formats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
+
+// ParseDuration parses a time taken given as seconds ("332"), "mm:ss" or "hh:mm:ss" into seconds
+func ParseDuration(value string) (int64, error) {
+	if value == "" {
+		return 0, errors.New("time taken is empty")
+	}
+
+	var seconds int64
+	for _, part := range strings.Split(value, ":") {
+		number, err := strconv.ParseInt(part, 10, 64)
+		if err != nil || number < 0 {
+			return 0, fmt.Errorf("invalid time taken '%s'", value)
+		}
+		seconds = seconds*60 + number
+	}
+
+	if seconds == 0 {
+		return 0, fmt.Errorf("invalid time taken '%s'", value)
+	}
+
+	return seconds, nil
+}
+
+// NormaliseExercise reduces WOD text to a form that can be compared between WODs
+func NormaliseExercise(exercise string) string {
+	var builder strings.Builder
+	space := false
+
+	for _, r := range strings.ToLower(exercise) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && builder.Len() > 0 {
+				builder.WriteRune(' ')
+			}
+			space = false
+			builder.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package data
+
+import (
+	"math"
+	"sort"
+
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// Formulas a one rep max can be estimated with
+const (
+	FormulaEpley    = "epley"
+	FormulaBrzycki  = "brzycki"
+	FormulaLombardi = "lombardi"
+)
+
+// MaxEstimateReps is the most reps in a set a one rep max is estimated from (the formulas get unreliable above it)
+const MaxEstimateReps = 12
+
+// ValidFormula checks formula is one of the known one rep max formulas
+func ValidFormula(formula string) bool {
+	switch formula {
+	case FormulaEpley, FormulaBrzycki, FormulaLombardi:
+		return true
+	}
+	return false
+}
+
+// EstimateOneRM estimates the most that could be lifted for a single rep from a set of reps at load, returning nil
+// if there are too many reps to estimate from
+func EstimateOneRM(formula string, load units.Measure, reps int) *units.Measure {
+	if reps < 1 || reps > MaxEstimateReps {
+		return nil
+	}
+
+	estimate := load.Amount
+	if reps > 1 {
+		r := float64(reps)
+		switch formula {
+		case FormulaBrzycki:
+			estimate = estimate * 36 / (37 - r)
+		case FormulaLombardi:
+			estimate = estimate * math.Pow(r, 0.1)
+		default:
+			estimate = estimate * (1 + r/30)
+		}
+	}
+
+	return &units.Measure{Amount: math.Round(estimate*10) / 10, Unit: load.Unit}
+}
+
+// BestLifts works out each movement's PR from lifts (which must have estimates in the same unit), ordered by
+// movement
+func BestLifts(lifts []Lift) []LiftPR {
+	byMovement := map[string]*LiftPR{}
+
+	for _, lift := range lifts {
+		pr, ok := byMovement[lift.Movement]
+		if !ok {
+			pr = &LiftPR{Movement: lift.Movement}
+			byMovement[lift.Movement] = pr
+		}
+
+		if lift.Load.Amount > pr.HeaviestLoad.Amount {
+			pr.HeaviestLoad = lift.Load
+		}
+
+		if lift.EstimatedOneRM != nil && lift.EstimatedOneRM.Amount > pr.EstimatedOneRM.Amount {
+			pr.EstimatedOneRM = *lift.EstimatedOneRM
+			pr.LiftID = lift.ID
+			pr.Date = lift.Date
+			pr.Reps = lift.Reps
+			pr.Load = lift.Load
+		}
+	}
+
+	prs := []LiftPR{}
+	for _, pr := range byMovement {
+		if pr.LiftID != 0 {
+			prs = append(prs, *pr)
+		}
+	}
+
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].Movement < prs[j].Movement
+	})
+
+	return prs
+}
+
+// Percentages returns working weights from 40% to 100% of a one rep max, rounded to the nearest half of its unit
+func Percentages(oneRM units.Measure) []LiftPercentage {
+	percentages := []LiftPercentage{}
+
+	for percent := 40; percent <= 100; percent += 5 {
+		load := units.Measure{
+			Amount: math.Round(oneRM.Amount*float64(percent)/100*2) / 2,
+			Unit:   oneRM.Unit,
+		}
+		percentages = append(percentages, LiftPercentage{Percent: percent, Load: load})
+	}
+
+	return percentages
+}
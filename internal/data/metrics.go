@@ -0,0 +1,150 @@
+package data
+
+import (
+	"math"
+
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// Types of metric
+const (
+	MetricBodyweight       = "bodyweight"
+	MetricBodyFat          = "bodyFat"
+	MetricRestingHeartRate = "restingHeartRate"
+	MetricBenchmark        = "benchmark"
+)
+
+// Units metrics other than bodyweight (stored in kilograms) and benchmarks are recorded in
+const (
+	UnitPercent        = "%"
+	UnitBeatsPerMinute = "bpm"
+)
+
+// ValidMetricType checks metricType is one of the known types of metric
+func ValidMetricType(metricType string) bool {
+	switch metricType {
+	case MetricBodyweight, MetricBodyFat, MetricRestingHeartRate, MetricBenchmark:
+		return true
+	}
+	return false
+}
+
+// SI converts a metric to the unit it's stored in (bodyweight without a unit is taken to be in system's units) and
+// fills in the unit of metrics that only have one
+func (m *MetricInput) SI(system units.System) error {
+	switch m.Type {
+	case MetricBodyweight:
+		weight, err := units.Measure{Amount: m.Value, Unit: m.Unit}.SI(units.Mass, system)
+		if err != nil {
+			return err
+		}
+		m.Value, m.Unit = weight.Amount, weight.Unit
+	case MetricBodyFat:
+		m.Unit = UnitPercent
+	case MetricRestingHeartRate:
+		m.Unit = UnitBeatsPerMinute
+	}
+
+	return nil
+}
+
+// In converts a bodyweight metric to system's units
+func (m *Metric) In(system units.System) {
+	if m.Type != MetricBodyweight {
+		return
+	}
+
+	weight := units.Measure{Amount: m.Value, Unit: m.Unit}.In(system)
+	m.Value, m.Unit = weight.Amount, weight.Unit
+}
+
+// In converts the units of a trend's values to system's units (only bodyweight changes)
+func (t *MetricTrend) In(system units.System) {
+	if t.Type != MetricBodyweight {
+		return
+	}
+
+	convert := func(value float64) float64 {
+		return units.Measure{Amount: value, Unit: t.Unit}.In(system).Amount
+	}
+
+	t.First, t.Latest = convert(t.First), convert(t.Latest)
+	t.Min, t.Max = convert(t.Min), convert(t.Max)
+	t.Average, t.Change = convert(t.Average), convert(t.Change)
+	t.Unit = units.DisplayUnit(units.Mass, system)
+}
+
+// MetricTrends summarises metrics (which must be oldest first) for each type and benchmark
+func MetricTrends(metrics []Metric) []MetricTrend {
+	trends := []MetricTrend{}
+	index := map[string]int{}
+
+	for _, metric := range metrics {
+		key := metric.Type
+		if metric.Name != nil {
+			key += "/" + *metric.Name
+		}
+
+		i, ok := index[key]
+		if !ok {
+			i = len(trends)
+			index[key] = i
+			trends = append(trends, MetricTrend{
+				Type:      metric.Type,
+				Name:      metric.Name,
+				Unit:      metric.Unit,
+				FirstDate: metric.Date,
+				First:     metric.Value,
+				Min:       metric.Value,
+				Max:       metric.Value,
+			})
+		}
+
+		trend := &trends[i]
+		trend.Count++
+		trend.LastDate = metric.Date
+		trend.Latest = metric.Value
+		trend.Min = math.Min(trend.Min, metric.Value)
+		trend.Max = math.Max(trend.Max, metric.Value)
+		trend.Average += (metric.Value - trend.Average) / float64(trend.Count)
+		trend.Change = trend.Latest - trend.First
+	}
+
+	for i := range trends {
+		trends[i].Average = math.Round(trends[i].Average*100) / 100
+		trends[i].Change = math.Round(trends[i].Change*100) / 100
+	}
+
+	return trends
+}
+
+// MetricAt returns the latest of metrics (which must be oldest first) recorded on or before date, so other stats
+// can be compared with it, or nil if there isn't one
+func MetricAt(metrics []Metric, date int64) *Metric {
+	var at *Metric
+
+	for i := range metrics {
+		if metrics[i].Date > date {
+			break
+		}
+		at = &metrics[i]
+	}
+
+	return at
+}
+
+// AddRelativeStrength compares each PR (in kilograms) with the bodyweight (oldest first, in kilograms) recorded
+// nearest before it
+func AddRelativeStrength(prs []LiftPR, bodyweights []Metric) {
+	for i := range prs {
+		bodyweight := MetricAt(bodyweights, prs[i].Date)
+		if bodyweight == nil || bodyweight.Value <= 0 {
+			continue
+		}
+
+		weight := *bodyweight
+		relative := math.Round(prs[i].EstimatedOneRM.Amount/weight.Value*100) / 100
+		prs[i].Bodyweight = &weight
+		prs[i].RelativeStrength = &relative
+	}
+}
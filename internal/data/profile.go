@@ -0,0 +1,110 @@
+package data
+
+import (
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Divisions a User can compete in on leaderboards
+const (
+	DivisionMen       = "men"
+	DivisionWomen     = "women"
+	DivisionNonBinary = "nonbinary"
+)
+
+// Limits on what can be put on a profile
+const (
+	MaxDisplayNameLength = 50
+	MinPasswordLength    = 8
+	EarliestBirthYear    = 1900
+	MaxUsernameLength    = 30
+)
+
+// DefaultTimeZone is the time zone of a User who hasn't picked one
+const DefaultTimeZone = "UTC"
+
+// ValidDivision checks division is one of the leaderboard divisions
+func ValidDivision(division string) bool {
+	switch division {
+	case DivisionMen, DivisionWomen, DivisionNonBinary:
+		return true
+	}
+	return false
+}
+
+// ValidTimeZone checks name is an IANA time zone (e.g. Europe/London)
+func ValidTimeZone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// Location returns the time zone called name (e.g. from a User's profile), or UTC if it isn't one
+func Location(name string) *time.Location {
+	if ValidTimeZone(name) {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// ValidAvatar checks avatar is an absolute http(s) URL
+func ValidAvatar(avatar string) bool {
+	u, err := url.Parse(avatar)
+	if err != nil {
+		return false
+	}
+
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// ValidBirthYear checks year is between EarliestBirthYear and now
+func ValidBirthYear(year int, now time.Time) bool {
+	return year >= EarliestBirthYear && year <= now.Year()
+}
+
+// NormaliseEmail tidies an email address so the same address is always stored the same way
+func NormaliseEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidEmail checks email is a bare email address (e.g. athlete@example.com)
+func ValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == email
+}
+
+// ValidDisplayName checks name isn't longer than MaxDisplayNameLength characters
+func ValidDisplayName(name string) bool {
+	return utf8.RuneCountInString(name) <= MaxDisplayNameLength
+}
+
+// NewUsername makes a username for an account created at single sign-on from the username the provider
+// suggests (or the email address), keeping only lower case letters, digits, dots, dashes and underscores
+func NewUsername(suggested string, email string) string {
+	if suggested == "" {
+		suggested = strings.SplitN(email, "@", 2)[0]
+	}
+
+	var username strings.Builder
+	for _, r := range strings.ToLower(suggested) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '.' || r == '-' || r == '_' {
+			username.WriteRune(r)
+		}
+		if username.Len() == MaxUsernameLength {
+			break
+		}
+	}
+
+	if username.Len() == 0 {
+		return "athlete"
+	}
+	return username.String()
+}
@@ -0,0 +1,61 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Sizes of a page of the feed or comments
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Reactions that can be left on an Activity or WOD
+const (
+	ReactionLike   = "like"
+	ReactionFire   = "fire"
+	ReactionStrong = "strong"
+	ReactionClap   = "clap"
+)
+
+// Statuses of a follow
+const (
+	FollowPending  = "pending"
+	FollowAccepted = "accepted"
+)
+
+// MaxCommentLength is the longest comment (in characters) that can be left
+const MaxCommentLength = 2000
+
+// ValidReaction checks reaction is one of the reactions that can be left
+func ValidReaction(reaction string) bool {
+	switch reaction {
+	case ReactionLike, ReactionFire, ReactionStrong, ReactionClap:
+		return true
+	}
+	return false
+}
+
+// EncodeCursor makes the cursor for the page after an item at date with id
+func EncodeCursor(date int64, id int64) string {
+	return fmt.Sprintf("%d_%d", date, id)
+}
+
+// DecodeCursor reads the date and id from a cursor made by EncodeCursor
+func DecodeCursor(cursor string) (int64, int64, error) {
+	parts := strings.Split(cursor, "_")
+	if len(parts) != 2 {
+		return 0, 0, errors.New("invalid cursor")
+	}
+
+	date, dateErr := strconv.ParseInt(parts[0], 10, 64)
+	id, idErr := strconv.ParseInt(parts[1], 10, 64)
+	if dateErr != nil || idErr != nil {
+		return 0, 0, errors.New("invalid cursor")
+	}
+
+	return date, id, nil
+}
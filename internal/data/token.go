@@ -0,0 +1,60 @@
+package data
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// What a single-use token emailed to a user is for
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+// How long tokens issued at login last (a refresh token is swapped for a new one each time it's used)
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// How long single-use tokens emailed to a user last
+const (
+	PasswordResetTTL     = time.Hour
+	EmailVerificationTTL = 24 * time.Hour
+)
+
+// OIDCLoginTTL is how long a user has to log in with the single sign-on provider and come back
+const OIDCLoginTTL = 10 * time.Minute
+
+// NewToken generates a random URL-safe token and the hash of it that should be stored
+func NewToken() (token string, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err = rand.Read(buf); err != nil {
+		return
+	}
+
+	token = base64.RawURLEncoding.EncodeToString(buf)
+	hash = HashToken(token)
+
+	return
+}
+
+// HashToken returns the hash a token is stored under
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewInviteCode generates a short random code that's easy to share
+func NewInviteCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.EncodeToString(buf), nil
+}
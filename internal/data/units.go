@@ -0,0 +1,113 @@
+package data
+
+import (
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// SI converts the loads and distances of a WOD's scaling options to the units they're stored in, taking those
+// without a unit to be in system's units
+func (w *WODInput) SI(system units.System) error {
+	for i := range w.Scaling {
+		if err := w.Scaling[i].SI(system); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// In converts the loads and distances of a WOD's scaling options to system's units
+func (w *WOD) In(system units.System) {
+	for i := range w.Scaling {
+		w.Scaling[i].In(system)
+	}
+}
+
+// SI converts a scaling option's load and distance to the units they're stored in. Distances given in calories
+// stay in calories.
+func (s *ScalingOption) SI(system units.System) error {
+	if s.Load != nil {
+		load, err := s.Load.SI(units.Mass, system)
+		if err != nil {
+			return err
+		}
+		s.Load = &load
+	}
+
+	if s.Distance != nil {
+		kind := units.Distance
+		if k, ok := s.Distance.Kind(); ok && k == units.Energy {
+			kind = units.Energy
+		}
+
+		distance, err := s.Distance.SI(kind, system)
+		if err != nil {
+			return err
+		}
+		s.Distance = &distance
+	}
+
+	return nil
+}
+
+// In converts a scaling option's load and distance to system's units
+func (s *ScalingOption) In(system units.System) {
+	if s.Load != nil {
+		load := s.Load.In(system)
+		s.Load = &load
+	}
+
+	if s.Distance != nil {
+		distance := s.Distance.In(system)
+		s.Distance = &distance
+	}
+}
+
+// SI converts the load of a lift to kilograms, taking a load without a unit to be in system's units
+func (l *LiftInput) SI(system units.System) error {
+	load, err := l.Load.SI(units.Mass, system)
+	if err != nil {
+		return err
+	}
+
+	l.Load = load
+	return nil
+}
+
+// In converts the load and estimated one rep max of a lift to system's units
+func (l *Lift) In(system units.System) {
+	l.Load = l.Load.In(system)
+
+	if l.EstimatedOneRM != nil {
+		oneRM := l.EstimatedOneRM.In(system)
+		l.EstimatedOneRM = &oneRM
+	}
+}
+
+// In converts the loads of a PR to system's units
+func (pr *LiftPR) In(system units.System) {
+	pr.EstimatedOneRM = pr.EstimatedOneRM.In(system)
+	pr.Load = pr.Load.In(system)
+	pr.HeaviestLoad = pr.HeaviestLoad.In(system)
+
+	if pr.Bodyweight != nil {
+		pr.Bodyweight.In(system)
+	}
+}
+
+// SI converts the load filters to kilograms, taking loads without a unit to be in system's units
+func (f *WODFilter) SI(system units.System) error {
+	for _, load := range []**units.Measure{&f.MinLoad, &f.MaxLoad} {
+		if *load == nil {
+			continue
+		}
+
+		value, err := (*load).SI(units.Mass, system)
+		if err != nil {
+			return err
+		}
+		*load = &value
+	}
+
+	return nil
+}
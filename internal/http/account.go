@@ -0,0 +1,207 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/mail"
+	"github.com/philLITERALLY/wodland-service/internal/ratelimit"
+)
+
+// mailTimeout is how long sending an email can take before giving up
+const mailTimeout = 30 * time.Second
+
+// Mail is what's needed to email users links back to the app
+type Mail struct {
+	Mailer mail.Mailer
+	// AppURL is where links in emails point (e.g. https://wodland.app)
+	AppURL string
+}
+
+// link returns the app link to path with token
+func (m Mail) link(path string, token string) string {
+	return strings.TrimSuffix(m.AppURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// send sends message, giving up after mailTimeout
+func (m Mail) send(ctx context.Context, message mail.Message) error {
+	ctx, cancel := context.WithTimeout(ctx, mailTimeout)
+	defer cancel()
+
+	return m.Mailer.Send(ctx, message)
+}
+
+// RequestPasswordReset will email a password reset link to the user with the email address given. The response
+// is the same whether or not anyone has that address, and requests are limited per address and per client.
+func RequestPasswordReset(dataSource *sql.DB, m Mail, limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resetInput := data.PasswordResetRequest{}
+
+		err := c.Bind(&resetInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with password reset details: %q", err))
+			return
+		}
+
+		email := data.NormaliseEmail(resetInput.Email)
+		if !data.ValidEmail(email) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid email address")
+			return
+		}
+
+		now := time.Now()
+		if !limiter.Allow("ip:"+clientIP(c), now) || !limiter.Allow("email:"+email, now) {
+			c.JSON(http.StatusTooManyRequests, "Too many password reset requests, please try again later")
+			return
+		}
+
+		userID, err := db.GetUserIDByEmail(dataSource, email)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusAccepted, "If an account has that email address a reset link has been sent to it")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading user: %q", err))
+			return
+		}
+
+		token, err := db.CreateUserToken(dataSource, userID, data.TokenPasswordReset, email, data.PasswordResetTTL)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating password reset: %q", err))
+			return
+		}
+
+		message := mail.PasswordReset(email, m.link("/reset-password", token), data.PasswordResetTTL)
+		if err := m.send(c, message); err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error sending password reset: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusAccepted, "If an account has that email address a reset link has been sent to it")
+	}
+}
+
+// ResetPassword will change the password of the user a password reset link was sent to
+func ResetPassword(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		resetInput := data.PasswordReset{}
+
+		err := c.Bind(&resetInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with password reset details: %q", err))
+			return
+		}
+
+		if resetInput.Token == "" {
+			c.JSON(http.StatusBadRequest, "Please provide the token from the reset link")
+			return
+		} else if utf8.RuneCountInString(resetInput.NewPassword) < data.MinPasswordLength {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Please provide a new password of at least %d characters", data.MinPasswordLength))
+			return
+		}
+
+		userID, err := db.ResetPassword(dataSource, resetInput.Token, resetInput.NewPassword)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, "Reset link is invalid, already used or has expired")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error resetting password: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditPasswordReset,
+			resourceType: data.ResourceUser,
+			resourceID:   userID,
+			after:        gin.H{"sessionsRevoked": "all"},
+			actor:        &data.User{ID: userID},
+		})
+
+		c.JSON(http.StatusOK, "Reset password")
+	}
+}
+
+// SendEmailVerification will email a link to verify the logged in user's email address (limited per user)
+func SendEmailVerification(dataSource *sql.DB, m Mail, limiter *ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		profile, err := db.GetProfile(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading profile: %q", err))
+			return
+		}
+
+		if profile.Email == nil {
+			c.JSON(http.StatusBadRequest, "Please add an email address to your profile first")
+			return
+		} else if profile.EmailVerified {
+			c.JSON(http.StatusBadRequest, "Your email address is already verified")
+			return
+		}
+
+		if !limiter.Allow("verify:"+strconv.Itoa(userID), time.Now()) {
+			c.JSON(http.StatusTooManyRequests, "Too many verification emails, please try again later")
+			return
+		}
+
+		if err := sendEmailVerification(c, dataSource, m, userID, *profile.Email); err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error sending verification email: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusAccepted, "Sent verification email")
+	}
+}
+
+// VerifyEmail will mark the email address a verification link was sent to as verified
+func VerifyEmail(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		verifyInput := data.EmailVerification{}
+
+		err := c.Bind(&verifyInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with verification details: %q", err))
+			return
+		}
+
+		err = db.VerifyEmail(dataSource, verifyInput.Token)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, "Verification link is invalid, already used or has expired")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error verifying email address: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Verified email address")
+	}
+}
+
+// sendEmailVerification creates a verification token for email and emails the link to it
+func sendEmailVerification(ctx context.Context, dataSource *sql.DB, m Mail, userID int, email string) error {
+	token, err := db.CreateUserToken(dataSource, userID, data.TokenEmailVerification, email, data.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return m.send(ctx, mail.EmailVerification(email, m.link("/verify-email", token), data.EmailVerificationTTL))
+}
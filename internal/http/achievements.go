@@ -0,0 +1,32 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// GetAchievements will get and return the badges a user has earned and their progress towards the rest
+func GetAchievements(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		achievementResult, err := db.AwardAchievements(dataSource, data.AchievementRules, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading achievements: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, achievementResult)
+	}
+}
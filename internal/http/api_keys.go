@@ -0,0 +1,110 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddAPIKey will create an API key for the user (the key is only ever returned here)
+func AddAPIKey(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		apiKeyInput := data.APIKeyInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&apiKeyInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with API key details: %q", err))
+			return
+		}
+
+		apiKeyInput.Name = strings.TrimSpace(apiKeyInput.Name)
+		if apiKeyInput.Name == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a name for the API key")
+			return
+		} else if len(apiKeyInput.Scopes) == 0 {
+			c.JSON(http.StatusBadRequest, "Please provide at least one scope (activities:read, activities:write or wods:read)")
+			return
+		} else if apiKeyInput.ExpiresAt != nil && *apiKeyInput.ExpiresAt <= time.Now().Unix() {
+			c.JSON(http.StatusBadRequest, "Please provide an expiry in the future")
+			return
+		}
+
+		for _, scope := range apiKeyInput.Scopes {
+			if !data.ValidScope(scope) {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("Invalid scope '%s' (must be activities:read, activities:write or wods:read)", scope))
+				return
+			}
+		}
+
+		apiKey, err := db.CreateAPIKey(dataSource, apiKeyInput, userID)
+		if err == db.ErrTooManyAPIKeys {
+			c.JSON(http.StatusConflict, fmt.Sprintf("You can have at most %d API keys, please revoke one first", data.MaxAPIKeys))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating API key: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, apiKey)
+	}
+}
+
+// GetAPIKeys will get and return the user's API keys (without the keys themselves)
+func GetAPIKeys(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		apiKeyResult, err := db.GetAPIKeys(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading API keys: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, apiKeyResult)
+	}
+}
+
+// RevokeAPIKey will revoke one of the user's API keys
+func RevokeAPIKey(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		keyID := c.Param("keyID")
+
+		err = db.RevokeAPIKey(dataSource, keyID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "API key not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error revoking API key: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Revoked API key")
+	}
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/lockout"
+)
+
+// requestIDHeader is the header a request's ID is read from (if a proxy in front of the service gave it one) and
+// written to
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey is where the request's ID is kept
+const requestIDKey = "requestID"
+
+// validRequestID is what a request ID given by the client has to look like to be used
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,64}$`)
+
+// RequestID gives each request an ID (keeping the one it came with if it has a sensible one), so entries in the
+// audit log can be matched up with the request that made them
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			id, _, err := data.NewToken()
+			if err != nil {
+				log.Printf("generating request ID err: %v", err)
+			}
+			requestID = id
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(requestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// change is a change to a resource to record in the audit log
+type change struct {
+	action       string
+	resourceType string
+	resourceID   interface{}
+	// before and after are snapshots of the resource (or the parts of it that changed), left out if nil
+	before interface{}
+	after  interface{}
+	// actor made the change (the logged in user if it isn't given)
+	actor *data.User
+}
+
+// audit records a change in the audit log. Failing to record it is logged rather than failing the request, as
+// the change has already been made by then.
+func audit(c *gin.Context, dataSource *sql.DB, change change) {
+	entry := data.AuditEntry{
+		Action:       change.action,
+		ResourceType: change.resourceType,
+		IP:           clientIP(c),
+		RequestID:    c.GetString(requestIDKey),
+		CreatedAt:    time.Now().Unix(),
+	}
+
+	if change.resourceID != nil {
+		entry.ResourceID = fmt.Sprint(change.resourceID)
+	}
+
+	actor := change.actor
+	if actor == nil {
+		actor, _ = GetUser(c)
+	}
+	if actor != nil && actor.ID != 0 {
+		entry.ActorID = &actor.ID
+		entry.Actor = actor.Username
+	}
+
+	var err error
+	if entry.Before, err = snapshot(change.before); err == nil {
+		entry.After, err = snapshot(change.after)
+	}
+	if err == nil {
+		err = db.AppendAudit(dataSource, entry)
+	}
+
+	if err != nil {
+		log.Printf("recording %s %s %s in audit log err: %v", entry.Action, entry.ResourceType, entry.ResourceID, err)
+	}
+}
+
+// AuditLoginEvents returns what to tell about login lockouts and unlocks so they're logged and recorded in the
+// audit log
+func AuditLoginEvents(dataSource *sql.DB) func(ctx context.Context, event lockout.Event) {
+	return func(ctx context.Context, event lockout.Event) {
+		lockout.LogEvent(ctx, event)
+
+		entry := data.AuditEntry{
+			Actor:        event.Actor,
+			Action:       event.Type,
+			ResourceType: data.ResourceLogin,
+			ResourceID:   event.Scope + ":" + event.Subject,
+			IP:           event.IP,
+			CreatedAt:    event.Time.Unix(),
+		}
+
+		if c, ok := ctx.(*gin.Context); ok {
+			entry.IP = clientIP(c)
+			entry.RequestID = c.GetString(requestIDKey)
+			if user, err := GetUser(c); err == nil {
+				entry.ActorID = &user.ID
+				entry.Actor = user.Username
+			}
+		}
+
+		var err error
+		if event.Type == lockout.EventLockout {
+			entry.After, err = snapshot(gin.H{"failures": event.Failures, "until": event.Until.Unix()})
+		}
+		if err == nil {
+			err = db.AppendAudit(dataSource, entry)
+		}
+
+		if err != nil {
+			log.Printf("recording %s in audit log err: %v", event.Type, err)
+		}
+	}
+}
+
+// GetAuditLog will get and return a page of the audit log (for admins)
+func GetAuditLog(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		filters, err := data.AuditFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		auditResult, err := db.GetAuditLog(dataSource, filters)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading audit log: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, auditResult)
+	}
+}
+
+// snapshot encodes a snapshot of a resource for the audit log (nil if there isn't one)
+func snapshot(resource interface{}) (json.RawMessage, error) {
+	if resource == nil {
+		return nil, nil
+	}
+	return json.Marshal(resource)
+}
@@ -0,0 +1,215 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// refreshTokenKey is where the refresh token for a new session is kept until the login response is written
+const refreshTokenKey = "refreshToken"
+
+// RequireAuth checks the request's access token the way the JWT middleware does, then that it hasn't been
+// denied and its session hasn't ended, before letting the logged in User through. API keys are accepted
+// instead of an access token only when they have all of scopes (so never when no scopes are given).
+func RequireAuth(mw *jwt.GinJWTMiddleware, dataSource *sql.DB, scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := apiKeyFromRequest(c); key != "" {
+			authenticateAPIKey(c, mw, dataSource, key, scopes)
+			return
+		}
+
+		claims, err := mw.GetClaimsFromJWT(c)
+		if err != nil {
+			unauthorized(c, mw, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(err, c))
+			return
+		}
+
+		if _, ok := claims["exp"].(float64); !ok {
+			unauthorized(c, mw, http.StatusBadRequest, mw.HTTPStatusMessageFunc(jwt.ErrMissingExpField, c))
+			return
+		}
+
+		c.Set("JWT_PAYLOAD", claims)
+
+		user, ok := mw.IdentityHandler(c).(*data.User)
+		tokenID, _ := claims["jti"].(string)
+		if !ok || tokenID == "" || user.Session == "" {
+			unauthorized(c, mw, http.StatusUnauthorized, "token has no session, please log in again")
+			return
+		}
+
+		revoked, err := db.TokenRevoked(dataSource, tokenID, user.Session, user.ID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, fmt.Sprintf("Error checking token: %q", err))
+			return
+		} else if revoked {
+			unauthorized(c, mw, http.StatusUnauthorized, "token has been revoked")
+			return
+		}
+
+		c.Set(mw.IdentityKey, user)
+		c.Next()
+	}
+}
+
+// authenticateAPIKey lets the User an API key belongs to through if the key has all of scopes
+func authenticateAPIKey(c *gin.Context, mw *jwt.GinJWTMiddleware, dataSource *sql.DB, key string, scopes []string) {
+	if len(scopes) == 0 {
+		unauthorized(c, mw, http.StatusForbidden, "API keys can't be used for this endpoint")
+		return
+	}
+
+	user, granted, err := db.GetAPIKeyUser(dataSource, key)
+	if err == sql.ErrNoRows {
+		unauthorized(c, mw, http.StatusUnauthorized, "API key is invalid, revoked or expired")
+		return
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, fmt.Sprintf("Error checking API key: %q", err))
+		return
+	}
+
+	if !data.HasScopes(granted, scopes) {
+		unauthorized(c, mw, http.StatusForbidden, fmt.Sprintf("API key needs the %s scope for this endpoint", strings.Join(scopes, ", ")))
+		return
+	}
+
+	c.Set(mw.IdentityKey, &user)
+	c.Next()
+}
+
+// apiKeyFromRequest returns the API key sent in the X-API-Key header or as an "ApiKey" Authorization header. Keys
+// aren't read from the query string, as URLs end up in request logs (and proxies' and browsers' histories).
+func apiKeyFromRequest(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+
+	if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && parts[0] == "ApiKey" {
+		return parts[1]
+	}
+
+	return ""
+}
+
+// StartSession starts a login session for the user, keeping its refresh token for LoginResponse
+func StartSession(c *gin.Context, dataSource *sql.DB, user *data.User) error {
+	sessionID, refreshToken, err := db.CreateSession(dataSource, user.ID)
+	if err != nil {
+		return err
+	}
+
+	user.Session = sessionID
+	c.Set(refreshTokenKey, refreshToken)
+
+	return nil
+}
+
+// LoginResponse writes the access token and the refresh token for the session started at login
+func LoginResponse(c *gin.Context, code int, token string, expire time.Time) {
+	tokenResponse(c, token, expire, c.GetString(refreshTokenKey))
+}
+
+// RefreshToken will swap a refresh token for a new one and a new access token. Using a refresh token a second
+// time ends its session.
+func RefreshToken(dataSource *sql.DB, mw *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		refreshInput := data.RefreshInput{}
+
+		err := c.Bind(&refreshInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with refresh details: %q", err))
+			return
+		}
+
+		user, refreshToken, err := db.RefreshSession(dataSource, refreshInput.RefreshToken)
+		if err == sql.ErrNoRows {
+			unauthorized(c, mw, http.StatusUnauthorized, "refresh token is invalid or has expired")
+			return
+		} else if err == db.ErrRefreshTokenReused {
+			unauthorized(c, mw, http.StatusUnauthorized, "refresh token has already been used, please log in again")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error refreshing session: %q", err))
+			return
+		}
+
+		token, expire, err := mw.TokenGenerator(&user)
+		if err != nil {
+			unauthorized(c, mw, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(jwt.ErrFailedTokenCreation, c))
+			return
+		}
+
+		tokenResponse(c, token, expire, refreshToken)
+	}
+}
+
+// Logout will end the logged in user's session and deny their access token
+func Logout(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := GetUser(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		claims := jwt.ExtractClaims(c)
+		tokenID, _ := claims["jti"].(string)
+		expire, _ := claims["exp"].(float64)
+
+		err = db.RevokeSession(dataSource, user.Session, tokenID, int64(expire), user.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error logging out: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK})
+	}
+}
+
+// LogoutEverywhere will end every one of the logged in user's sessions (on every device)
+func LogoutEverywhere(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = db.RevokeAllSessions(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error logging out: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"code": http.StatusOK})
+	}
+}
+
+// tokenResponse writes a new access token and refresh token
+func tokenResponse(c *gin.Context, token string, expire time.Time, refreshToken string) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":         http.StatusOK,
+		"token":        token,
+		"expire":       expire.Format(time.RFC3339),
+		"refreshToken": refreshToken,
+	})
+}
+
+// unauthorized aborts the request with the JWT middleware's unauthorized response
+func unauthorized(c *gin.Context, mw *jwt.GinJWTMiddleware, code int, message string) {
+	c.Header("WWW-Authenticate", "JWT realm="+mw.Realm)
+	c.Abort()
+	mw.Unauthorized(c, code, message)
+}
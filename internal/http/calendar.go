@@ -0,0 +1,181 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/ical"
+)
+
+// CreateCalendarToken will create a calendar feed token (revoking any existing one)
+func CreateCalendarToken(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		token, tokenHash, err := data.NewToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating calendar token: %q", err))
+			return
+		}
+
+		err = db.CreateCalendarToken(dataSource, tokenHash, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating calendar token: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"token": token,
+			"url":   "/calendar/" + token + ".ics",
+		})
+	}
+}
+
+// RevokeCalendarToken will stop the user's calendar feed from working
+func RevokeCalendarToken(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = db.RevokeCalendarTokens(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error revoking calendar token: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Revoked calendar token")
+	}
+}
+
+// GetCalendar will return a user's Activities and the sessions they still have planned as an iCalendar
+// feed. Calendar clients can't send a JWT so the user is identified by the token in the URL instead.
+func GetCalendar(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+		userID, err := db.GetCalendarTokenUser(dataSource, data.HashToken(token))
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Calendar not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading calendar token: %q", err))
+			return
+		}
+
+		completed := false
+		planned, err := db.GetPlannedSessions(dataSource, &data.PlannedSessionFilter{Completed: &completed}, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading planned sessions: %q", err))
+			return
+		}
+
+		c.Header("Content-Type", "text/calendar; charset=utf-8")
+		c.Header("Content-Disposition", "inline; filename=\"wodland.ics\"")
+		c.Status(http.StatusOK)
+
+		encoder := ical.NewEncoder(c.Writer, "WODland")
+
+		for _, session := range planned {
+			if err := encoder.Encode(plannedSessionEvent(session, data.Location(session.TimeZone))); err != nil {
+				fmt.Printf("error writing calendar: %+v", err)
+				return
+			}
+		}
+
+		err = db.ExportActivities(dataSource, &data.ActivityFilter{}, userID, func(activity data.Activity) error {
+			return encoder.Encode(activityEvent(activity))
+		})
+		if err != nil {
+			// Headers have already been sent so all we can do is stop the stream
+			fmt.Printf("error writing calendar: %+v", err)
+			return
+		}
+
+		if err := encoder.Close(); err != nil {
+			fmt.Printf("error writing calendar: %+v", err)
+		}
+	}
+}
+
+// activityEvent describes a completed Activity as a calendar event
+func activityEvent(activity data.Activity) ical.Event {
+	event := ical.Event{
+		UID:      fmt.Sprintf("activity-%d@wodland", activity.ID),
+		Start:    time.Unix(activity.Date, 0),
+		Duration: time.Duration(activity.TimeTaken) * time.Second,
+	}
+
+	var description []string
+	if wod := activity.WOD; wod != nil {
+		event.Summary = wodTitle(*wod)
+		if wod.Exercise != nil {
+			description = append(description, *wod.Exercise)
+		}
+	}
+	if activity.Notes != nil && *activity.Notes != "" {
+		description = append(description, *activity.Notes)
+	}
+	event.Description = strings.Join(description, "\n\n")
+
+	return event
+}
+
+// plannedSessionEvent describes a planned session as an all day calendar event on its date in loc (the time zone it
+// was planned in)
+func plannedSessionEvent(session data.PlannedSession, loc *time.Location) ical.Event {
+	event := ical.Event{
+		UID:     fmt.Sprintf("planned-%d@wodland", session.ID),
+		Start:   time.Unix(session.PlannedDate, 0).In(loc),
+		AllDay:  true,
+		Summary: "Planned",
+	}
+
+	var description []string
+	if wod := session.WOD; wod != nil {
+		event.Summary = "Planned: " + wodTitle(*wod)
+		if wod.Exercise != nil {
+			description = append(description, *wod.Exercise)
+		}
+	}
+	if session.TargetScore != nil {
+		description = append(description, "Target: "+formatTimeTaken(*session.TargetScore))
+	}
+	event.Description = strings.Join(description, "\n\n")
+
+	return event
+}
+
+// formatTimeTaken formats seconds as m:ss (or h:mm:ss)
+func formatTimeTaken(seconds int64) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
+
+// wodTitle names a WOD from its type and source
+func wodTitle(wod data.WOD) string {
+	if wod.Source != nil && *wod.Source != "" {
+		return fmt.Sprintf("%s: %s", wod.Type, *wod.Source)
+	}
+	if wod.Type != "" {
+		return wod.Type
+	}
+	return "WOD"
+}
@@ -0,0 +1,49 @@
+package http
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+)
+
+// clientIPKey is where the client's IP address is kept
+const clientIPKey = "clientIP"
+
+// ClientIP works out the client's IP address once per request. Only the last proxyHops entries of
+// X-Forwarded-For were added by proxies the service trusts (each one appends the address that connected to it),
+// so the client is the entry proxyHops from the end: anything before it was sent by the client and can be made up.
+// With no trusted proxies the address that connected is used. The router's ForwardedByClientIP should be off so
+// c.ClientIP doesn't trust the header either.
+func ClientIP(proxyHops int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+
+		if proxyHops > 0 {
+			var forwarded []string
+			for _, header := range c.Request.Header.Values("X-Forwarded-For") {
+				for _, entry := range strings.Split(header, ",") {
+					forwarded = append(forwarded, strings.TrimSpace(entry))
+				}
+			}
+
+			if len(forwarded) >= proxyHops {
+				if hop := forwarded[len(forwarded)-proxyHops]; net.ParseIP(hop) != nil {
+					ip = hop
+				}
+			}
+		}
+
+		c.Set(clientIPKey, ip)
+		c.Next()
+	}
+}
+
+// clientIP returns the client's IP address worked out by ClientIP
+func clientIP(c *gin.Context) string {
+	if ip := c.GetString(clientIPKey); ip != "" {
+		return ip
+	}
+	return c.ClientIP()
+}
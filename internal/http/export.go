@@ -0,0 +1,189 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// exportFlushEvery is how many rows are written between flushes of the response
+const exportFlushEvery = 100
+
+var exportHeader = []string{
+	"activityID", "date", "timeTaken", "meps", "exertion", "notes",
+	"wodID", "wodSource", "wodCreationDate", "wodExercise", "wodPicture", "wodType",
+	"tier", "substitutions",
+}
+
+// ExportActivities will stream all of a user's Activities (with their WOD) as csv, json or ndjson
+func ExportActivities(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.ActivityFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		var writeRow func(data.Activity) error
+		var finish func() error
+
+		format := c.DefaultQuery("format", "csv")
+		switch format {
+		case "csv":
+			writer := csv.NewWriter(c.Writer)
+			if err := writer.Write(exportHeader); err != nil {
+				c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error exporting activities: %q", err))
+				return
+			}
+			writeRow = func(activity data.Activity) error {
+				return writer.Write(activityRecord(activity))
+			}
+			finish = func() error {
+				writer.Flush()
+				return writer.Error()
+			}
+		case "json":
+			encoder := json.NewEncoder(c.Writer)
+			count := 0
+			writeRow = func(activity data.Activity) error {
+				separator := ","
+				if count == 0 {
+					separator = "["
+				}
+				count++
+				if _, err := c.Writer.WriteString(separator); err != nil {
+					return err
+				}
+				return encoder.Encode(activity)
+			}
+			finish = func() error {
+				closing := "]"
+				if count == 0 {
+					closing = "[]"
+				}
+				_, err := c.Writer.WriteString(closing)
+				return err
+			}
+		case "ndjson":
+			encoder := json.NewEncoder(c.Writer)
+			writeRow = func(activity data.Activity) error {
+				return encoder.Encode(activity)
+			}
+			finish = func() error {
+				return nil
+			}
+		default:
+			c.JSON(http.StatusBadRequest, "Please provide a valid format (csv, json or ndjson)")
+			return
+		}
+
+		c.Header("Content-Type", exportContentTypes[format])
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"wodland-export.%s\"", format))
+		c.Status(http.StatusOK)
+
+		rowCount := 0
+		err = db.ExportActivities(dataSource, filters, userID, func(activity data.Activity) error {
+			if err := writeRow(activity); err != nil {
+				return err
+			}
+
+			rowCount++
+			if rowCount%exportFlushEvery == 0 {
+				c.Writer.Flush()
+			}
+
+			return nil
+		})
+		if err != nil {
+			// Headers have already been sent so all we can do is stop the stream
+			fmt.Printf("error exporting activities: %+v", err)
+			return
+		}
+
+		if err := finish(); err != nil {
+			fmt.Printf("error finishing export: %+v", err)
+			return
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+var exportContentTypes = map[string]string{
+	"csv":    "text/csv; charset=utf-8",
+	"json":   "application/json; charset=utf-8",
+	"ndjson": "application/x-ndjson; charset=utf-8",
+}
+
+// activityRecord flattens an Activity into a csv record matching exportHeader
+func activityRecord(activity data.Activity) []string {
+	record := []string{
+		strconv.FormatInt(activity.ID, 10),
+		formatUnix(activity.Date),
+		strconv.FormatInt(activity.TimeTaken, 10),
+		formatOptionalInt64(activity.MEPs),
+		formatOptionalInt64(activity.Exertion),
+		csvText(formatOptionalString(activity.Notes)),
+		"", "", "", "", "", "",
+		formatOptionalString(activity.Tier),
+		csvText(formatOptionalString(activity.Substitutions)),
+	}
+
+	if wod := activity.WOD; wod != nil {
+		record[6] = strconv.Itoa(wod.ID)
+		record[7] = csvText(formatOptionalString(wod.Source))
+		record[8] = formatUnix(wod.CreationT)
+		record[9] = csvText(formatOptionalString(wod.Exercise))
+		record[10] = csvText(formatOptionalString(wod.Picture))
+		record[11] = csvText(wod.Type)
+	}
+
+	return record
+}
+
+// csvText stops free text being run as a formula when the export is opened in a spreadsheet, by starting any
+// cell a spreadsheet would treat as one with a quote
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+func formatUnix(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
+
+func formatOptionalInt64(value *int64) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.FormatInt(*value, 10)
+}
+
+func formatOptionalString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,315 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// FollowUser will follow another user (or ask to, if their profile is private)
+func FollowUser(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followeeID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		status, err := db.FollowUser(dataSource, followeeID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error following user: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"status": status})
+	}
+}
+
+// UnfollowUser will stop following another user (or take back a request to)
+func UnfollowUser(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followeeID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		err = db.UnfollowUser(dataSource, followeeID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Follow not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error unfollowing user: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Unfollowed user")
+	}
+}
+
+// GetFollowing will get and return who the user follows (or has asked to)
+func GetFollowing(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followingResult, err := db.GetFollowing(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading following: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, followingResult)
+	}
+}
+
+// GetFollowers will get and return who follows the user
+func GetFollowers(dataSource *sql.DB) gin.HandlerFunc {
+	return getFollowers(dataSource, data.FollowAccepted)
+}
+
+// GetFollowRequests will get and return who is waiting for the user to accept them as a follower
+func GetFollowRequests(dataSource *sql.DB) gin.HandlerFunc {
+	return getFollowers(dataSource, data.FollowPending)
+}
+
+// getFollowers gets and returns the user's followers with status
+func getFollowers(dataSource *sql.DB, status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followerResult, err := db.GetFollowers(dataSource, status, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading followers: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, followerResult)
+	}
+}
+
+// AcceptFollower will accept another user's request to follow the user
+func AcceptFollower(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followerID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		err = db.AcceptFollower(dataSource, followerID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Follow request not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error accepting follower: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Accepted follower")
+	}
+}
+
+// RemoveFollower will stop another user following the user (or turn down their request to)
+func RemoveFollower(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		followerID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		err = db.RemoveFollower(dataSource, followerID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Follower not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error removing follower: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Removed follower")
+	}
+}
+
+// BlockUser will block another user, ending any follows between them
+func BlockUser(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		blockedID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		err = db.BlockUser(dataSource, blockedID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error blocking user: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Blocked user")
+	}
+}
+
+// UnblockUser will unblock another user
+func UnblockUser(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		blockedID, ok := otherUserID(c, userID)
+		if !ok {
+			return
+		}
+
+		err = db.UnblockUser(dataSource, blockedID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Block not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error unblocking user: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Unblocked user")
+	}
+}
+
+// GetBlocked will get and return who the user has blocked
+func GetBlocked(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		blockedResult, err := db.GetBlocked(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading blocked users: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, blockedResult)
+	}
+}
+
+// GetPrivacy will return whether the user has to accept people before they can follow them
+func GetPrivacy(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		privacyResult, err := db.GetPrivacy(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading privacy: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, privacyResult)
+	}
+}
+
+// UpdatePrivacy will change whether the user has to accept people before they can follow them
+func UpdatePrivacy(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		privacyInput := data.Privacy{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&privacyInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with privacy details: %q", err))
+			return
+		}
+
+		err = db.UpdatePrivacy(dataSource, privacyInput, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating privacy: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, privacyInput)
+	}
+}
+
+// otherUserID reads the user being followed, blocked or the like from the path, writing an error response if
+// it isn't a valid ID or is the user themselves
+func otherUserID(c *gin.Context, userID int) (int, bool) {
+	otherID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "Please provide a valid user ID")
+		return 0, false
+	} else if otherID == userID {
+		c.JSON(http.StatusBadRequest, "Please provide another user's ID")
+		return 0, false
+	}
+
+	return otherID, true
+}
@@ -0,0 +1,250 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddGoal will set a goal
+func AddGoal(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		goalInput := data.GoalInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&goalInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with goal details: %q", err))
+			return
+		}
+
+		loc, err := db.GetLocation(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading time zone: %q", err))
+			return
+		}
+
+		if !validGoal(c, &goalInput, loc) {
+			return
+		}
+
+		goalID, err := db.CreateGoal(dataSource, goalInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating goal: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": goalID})
+	}
+}
+
+// GetGoals will get and return a user's goals with their progress
+func GetGoals(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		goalResult, err := db.GetGoals(dataSource, userID, time.Now().Unix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading goals: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, goalResult)
+	}
+}
+
+// GetGoal will get and return an individual goal with its progress
+func GetGoal(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		goalID := c.Param("goalID")
+
+		goalResult, err := db.GetGoal(dataSource, goalID, userID, time.Now().Unix())
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Goal not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading goal: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, goalResult)
+	}
+}
+
+// UpdateGoal will change a goal
+func UpdateGoal(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		goalInput := data.GoalInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&goalInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with goal details: %q", err))
+			return
+		}
+
+		loc, err := db.GetLocation(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading time zone: %q", err))
+			return
+		}
+
+		if !validGoal(c, &goalInput, loc) {
+			return
+		}
+
+		goalID := c.Param("goalID")
+
+		err = db.UpdateGoal(dataSource, goalID, goalInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Goal not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating goal: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Updated goal")
+	}
+}
+
+// DeleteGoal will delete a goal
+func DeleteGoal(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		goalID := c.Param("goalID")
+
+		err = db.DeleteGoal(dataSource, goalID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Goal not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting goal: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Deleted goal")
+	}
+}
+
+// GetGoalSummary will count a user's goals by status
+func GetGoalSummary(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		goalResult, err := db.GetGoals(dataSource, userID, time.Now().Unix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading goals: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, data.SummariseGoals(goalResult))
+	}
+}
+
+// validGoal checks a goal and fills in its defaults, writing an error response if it isn't valid
+func validGoal(c *gin.Context, goal *data.GoalInput, loc *time.Location) bool {
+	if goal.Name == "" {
+		c.JSON(http.StatusBadRequest, "Please provide a name")
+		return false
+	} else if !data.ValidGoalType(goal.Type) {
+		c.JSON(http.StatusBadRequest, "Please provide a type (score, frequency or volume)")
+		return false
+	} else if goal.Target <= 0 {
+		c.JSON(http.StatusBadRequest, "Please provide a target")
+		return false
+	}
+
+	if goal.StartDate == 0 {
+		goal.StartDate = data.StartOfDay(time.Now().Unix(), loc)
+	}
+
+	if goal.Deadline != nil && *goal.Deadline <= goal.StartDate {
+		c.JSON(http.StatusBadRequest, "Please provide a deadline after the start date")
+		return false
+	}
+
+	if goal.Type == data.GoalScore {
+		if goal.WODID == nil {
+			c.JSON(http.StatusBadRequest, "Please provide the WOD ID of a score goal")
+			return false
+		}
+		if goal.Tier == nil {
+			tier := data.TierRx
+			goal.Tier = &tier
+		} else if !data.ValidTier(*goal.Tier) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid tier (Rx, Scaled or Foundations)")
+			return false
+		}
+		goal.Measure, goal.Period = nil, nil
+		return true
+	}
+
+	goal.WODID, goal.Tier = nil, nil
+
+	if goal.Period == nil {
+		period := data.PeriodWeek
+		goal.Period = &period
+	} else if !data.ValidPeriod(*goal.Period) {
+		c.JSON(http.StatusBadRequest, "Please provide a period (week or month)")
+		return false
+	}
+
+	if goal.Type == data.GoalFrequency {
+		goal.Measure = nil
+	} else if goal.Measure == nil {
+		measure := data.MeasureTime
+		goal.Measure = &measure
+	} else if !data.ValidMeasure(*goal.Measure) {
+		c.JSON(http.StatusBadRequest, "Please provide a measure (time or meps)")
+		return false
+	}
+
+	return true
+}
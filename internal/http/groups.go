@@ -0,0 +1,126 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddGroup will create a group of athletes
+func AddGroup(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupInput := data.GroupInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&groupInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with group details: %q", err))
+			return
+		}
+
+		if groupInput.Name == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a name")
+			return
+		}
+
+		groupID, err := db.CreateGroup(dataSource, groupInput, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating group: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": groupID})
+	}
+}
+
+// GetGroups will get and return the groups a coach has
+func GetGroups(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		groupResult, err := db.GetGroups(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading groups: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, groupResult)
+	}
+}
+
+// AddGroupMembers will add athletes to a group
+func AddGroupMembers(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		groupInput := data.GroupInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&groupInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with group details: %q", err))
+			return
+		}
+
+		groupID := c.Param("groupID")
+
+		err = db.AddGroupMembers(dataSource, groupID, groupInput.MemberIDs, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Group not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error adding group members: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Added group members")
+	}
+}
+
+// RemoveGroupMember will remove an athlete from a group
+func RemoveGroupMember(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		groupID := c.Param("groupID")
+		memberID := c.Param("userID")
+
+		err = db.RemoveGroupMember(dataSource, groupID, memberID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Group not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error removing group member: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Removed group member")
+	}
+}
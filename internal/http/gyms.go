@@ -0,0 +1,281 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddGym will create a Gym owned by the user
+func AddGym(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		gymInput := data.GymInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&gymInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with gym details: %q", err))
+			return
+		}
+
+		if gymInput.Name == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a name")
+			return
+		}
+
+		gymID, err := db.CreateGym(dataSource, gymInput, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating gym: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": gymID})
+	}
+}
+
+// GetGyms will get and return the Gyms a user is a member of
+func GetGyms(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		gymResult, err := db.GetGyms(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading gyms: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gymResult)
+	}
+}
+
+// GetGymMembers will get and return the members of a Gym
+func GetGymMembers(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		gymID := c.Param("gymID")
+
+		memberResult, err := db.GetGymMembers(dataSource, gymID, userID)
+		if err != nil {
+			gymError(c, err, "Error reading gym members")
+			return
+		}
+
+		c.JSON(http.StatusOK, memberResult)
+	}
+}
+
+// JoinGym will add the user to the Gym an invite code belongs to
+func JoinGym(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var joinInput struct {
+			InviteCode string `json:"inviteCode"`
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&joinInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with invite details: %q", err))
+			return
+		}
+
+		gymID, err := db.JoinGym(dataSource, joinInput.InviteCode, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Invite code not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error joining gym: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"id": gymID})
+	}
+}
+
+// RotateInviteCode will replace a Gym's invite code so old invites stop working
+func RotateInviteCode(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		gymID := c.Param("gymID")
+
+		inviteCode, err := db.RotateInviteCode(dataSource, gymID, userID)
+		if err != nil {
+			gymError(c, err, "Error creating invite code")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"inviteCode": inviteCode})
+	}
+}
+
+// UpdateGymMemberRole will change a member's role in a Gym
+func UpdateGymMemberRole(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var roleInput struct {
+			Role string `json:"role"`
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&roleInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with role details: %q", err))
+			return
+		}
+
+		switch roleInput.Role {
+		case data.GymRoleOwner, data.GymRoleCoach, data.GymRoleAthlete:
+		default:
+			c.JSON(http.StatusBadRequest, "Please provide a role (owner, coach or athlete)")
+			return
+		}
+
+		gymID := c.Param("gymID")
+		memberID := c.Param("userID")
+
+		previousRole, err := db.UpdateGymMemberRole(dataSource, gymID, memberID, roleInput.Role, userID)
+		if err != nil {
+			gymError(c, err, "Error updating gym member")
+			return
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditRoleChange,
+			resourceType: data.ResourceGymMember,
+			resourceID:   gymID + ":" + memberID,
+			before:       gin.H{"role": previousRole},
+			after:        gin.H{"role": roleInput.Role},
+		})
+
+		c.JSON(http.StatusOK, "Updated gym member")
+	}
+}
+
+// RemoveGymMember will remove a member from a Gym (or let a member leave)
+func RemoveGymMember(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		gymID := c.Param("gymID")
+
+		memberID, err := strconv.Atoi(c.Param("userID"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Please provide a valid user ID")
+			return
+		}
+
+		err = db.RemoveGymMember(dataSource, gymID, memberID, userID)
+		if err != nil {
+			gymError(c, err, "Error removing gym member")
+			return
+		}
+
+		c.JSON(http.StatusOK, "Removed gym member")
+	}
+}
+
+// GetLeaderboard will get and return the best time of each Gym member for a WOD (can be filtered by tier, division
+// and to people the user follows)
+func GetLeaderboard(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		gymID := c.Param("gymID")
+		wodID := c.Param("wodID")
+
+		filters, err := data.LeaderboardFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		leaderboardResult, err := db.GetLeaderboard(dataSource, gymID, wodID, filters, userID)
+		if err != nil {
+			gymError(c, err, "Error reading leaderboard")
+			return
+		}
+
+		c.JSON(http.StatusOK, leaderboardResult)
+	}
+}
+
+// canPostToGym checks the user is allowed to add WODs to a Gym, writing an error response if not
+func canPostToGym(c *gin.Context, dataSource *sql.DB, gymID int, userID int) bool {
+	role, err := db.GetGymRole(dataSource, strconv.Itoa(gymID), userID)
+	if err == nil && role == data.GymRoleAthlete {
+		err = db.ErrForbidden
+	}
+
+	if err != nil {
+		gymError(c, err, "Error reading gym")
+		return false
+	}
+
+	return true
+}
+
+// gymError writes the response for an error from a Gym query
+func gymError(c *gin.Context, err error, message string) {
+	switch err {
+	case sql.ErrNoRows:
+		c.JSON(http.StatusNotFound, "Gym not found")
+	case db.ErrForbidden:
+		c.JSON(http.StatusForbidden, "You don't have permission to do that")
+	case db.ErrLastGymOwner:
+		c.JSON(http.StatusConflict, "A gym needs at least one owner, please make someone else an owner first")
+	default:
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("%s: %q", message, err))
+	}
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/wearable"
+)
+
+// GetMaxHeartRate will get and return the max heart rate the user's zones are worked out from
+func GetMaxHeartRate(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		maxHeartRate, err := db.GetMaxHeartRate(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading max heart rate: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"maxHeartRate": maxHeartRate})
+	}
+}
+
+// UpdateMaxHeartRate will change the max heart rate the user's zones are worked out from
+func UpdateMaxHeartRate(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var heartRateInput struct {
+			MaxHeartRate int `json:"maxHeartRate"`
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&heartRateInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with heart rate details: %q", err))
+			return
+		}
+
+		if heartRateInput.MaxHeartRate < 100 || heartRateInput.MaxHeartRate > 250 {
+			c.JSON(http.StatusBadRequest, "Please provide a max heart rate (100 to 250)")
+			return
+		}
+
+		err = db.UpdateMaxHeartRate(dataSource, heartRateInput.MaxHeartRate, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating max heart rate: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Updated max heart rate")
+	}
+}
+
+// AddActivityFromFile will create an Activity (the "activity" form field) with the heart rate from a FIT, TCX or
+// GPX file, using the file for the date, time taken and MEPs if they aren't given
+func AddActivityFromFile(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		activityInput := data.ActivityInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		if activity := c.PostForm("activity"); activity != "" {
+			if err := json.Unmarshal([]byte(activity), &activityInput); err != nil {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with Activity details: %q", err))
+				return
+			}
+		}
+
+		if activityInput.WODID == nil {
+			c.JSON(http.StatusBadRequest, "Please provide a WOD ID")
+			return
+		} else if !validActivityTier(c, activityInput) {
+			return
+		}
+
+		summary, ok := readHeartRate(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		if activityInput.Date == 0 {
+			activityInput.Date = summary.Start
+		}
+		if activityInput.TimeTaken == 0 {
+			activityInput.TimeTaken = summary.Duration
+		}
+		if activityInput.MEPs == nil {
+			activityInput.MEPs = &summary.MEPs
+		}
+
+		if activityInput.TimeTaken == 0 {
+			c.JSON(http.StatusBadRequest, "Please provide a time taken")
+			return
+		}
+
+		activityID, err := db.CreateActivityWithHeartRate(dataSource, activityInput, summary, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating activity: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceActivity, resourceID: activityID, after: activityInput})
+
+		activitiesAdded(dataSource, userID)
+
+		c.JSON(http.StatusCreated, data.HeartRate{ActivityID: activityID, Summary: summary})
+	}
+}
+
+// UploadHeartRate will add the heart rate from a FIT, TCX or GPX file to an Activity, filling in its MEPs if they
+// weren't given
+func UploadHeartRate(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		activityID, err := strconv.ParseInt(c.Param("activityID"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Please provide a valid activity ID")
+			return
+		}
+
+		summary, ok := readHeartRate(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		err = db.AddHeartRate(dataSource, activityID, summary, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Activity not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error adding heart rate: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{action: data.AuditUpdate, resourceType: data.ResourceActivity, resourceID: activityID, after: gin.H{"heartRate": summary}})
+
+		activitiesAdded(dataSource, userID)
+
+		c.JSON(http.StatusOK, data.HeartRate{ActivityID: activityID, Summary: summary})
+	}
+}
+
+// GetHeartRate will get and return the heart rate summary of an Activity
+func GetHeartRate(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		activityID := c.Param("activityID")
+
+		heartRateResult, err := db.GetHeartRate(dataSource, activityID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Heart rate not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading heart rate: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, heartRateResult)
+	}
+}
+
+// readHeartRate reads the "file" of a multipart upload and summarises it with the user's max heart rate, writing
+// an error response if it can't
+func readHeartRate(c *gin.Context, dataSource *sql.DB, userID int) (wearable.Summary, bool) {
+	maxHeartRate, err := db.GetMaxHeartRate(dataSource, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading max heart rate: %q", err))
+		return wearable.Summary{}, false
+	} else if maxHeartRate == nil {
+		c.JSON(http.StatusBadRequest, "Please set your max heart rate first")
+		return wearable.Summary{}, false
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "Please provide a FIT, TCX or GPX file")
+		return wearable.Summary{}, false
+	}
+
+	if fileHeader.Size > wearable.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("File must be smaller than %dMB", wearable.MaxSize>>20))
+		return wearable.Summary{}, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error opening file: %q", err))
+		return wearable.Summary{}, false
+	}
+	defer file.Close()
+
+	samples, err := wearable.Parse(fileHeader.Filename, io.LimitReader(file, wearable.MaxSize))
+	if err == wearable.ErrUnsupported {
+		c.JSON(http.StatusUnsupportedMediaType, err.Error())
+		return wearable.Summary{}, false
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading file: %q", err))
+		return wearable.Summary{}, false
+	}
+
+	return wearable.Summarise(samples, *maxHeartRate), true
+}
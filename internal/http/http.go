@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/heroku/x/hmetrics/onload"
@@ -24,6 +25,37 @@ func GetUserID(c *gin.Context) (int, error) {
 	return user.(*data.User).ID, nil
 }
 
+// GetUser will return the logged in User
+func GetUser(c *gin.Context) (*data.User, error) {
+	user, exists := c.Get(usernameKey)
+	if !exists {
+		return nil, errors.New("Error fetching logged in user")
+	}
+
+	return user.(*data.User), nil
+}
+
+// RequireRole will only allow Users with one of the roles through
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := GetUser(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.AbortWithStatusJSON(http.StatusBadRequest, err)
+			return
+		}
+
+		for _, role := range roles {
+			if user.Role == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, "You don't have permission to do that")
+	}
+}
+
 // GetWOD will get and return an individual WOD
 func GetWOD(dataSource *sql.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -35,12 +67,22 @@ func GetWOD(dataSource *sql.DB) gin.HandlerFunc {
 
 		wodID := c.Param("wodID")
 
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
 		wodResult, err := db.GetWOD(dataSource, wodID, userID)
-		if err != nil {
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading wod: %q", err))
 			return
 		}
 
+		wodResult.In(system)
+
 		c.JSON(http.StatusOK, wodResult)
 	}
 }
@@ -60,12 +102,26 @@ func GetWODs(dataSource *sql.DB) gin.HandlerFunc {
 			return
 		}
 
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		if err := filters.SI(system); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
 		wodResult, err := db.GetWODs(dataSource, filters, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading wods: %q", err))
 			return
 		}
 
+		for i := range wodResult {
+			wodResult[i].In(system)
+		}
+
 		c.JSON(http.StatusOK, wodResult)
 	}
 }
@@ -99,18 +155,52 @@ func AddWOD(dataSource *sql.DB) gin.HandlerFunc {
 			} else if wodInput.TimeTaken == 0 {
 				c.JSON(http.StatusBadRequest, "Please provide a time taken for activity")
 				return
+			} else if !validActivityTier(c, *wodInput.ActivityInput) {
+				return
 			}
 		}
 
-		err = db.CreateWOD(dataSource, wodInput, userID)
+		if !validScaling(c, wodInput.WODInput) {
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		if err := wodInput.WODInput.SI(system); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with scaling: %q", err))
+			return
+		}
+
+		if wodInput.Visibility != "" && !data.ValidVisibility(wodInput.Visibility) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid visibility (private, gym or public)")
+			return
+		} else if wodInput.Visibility == data.VisibilityGym && wodInput.GymID == nil {
+			c.JSON(http.StatusBadRequest, "Please provide a gym ID for a WOD visible to a gym")
+			return
+		}
+
+		if wodInput.GymID != nil && !canPostToGym(c, dataSource, *wodInput.GymID, userID) {
+			return
+		}
+
+		wodID, activityID, err := db.CreateWOD(dataSource, wodInput, userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating WOD: %q", err))
 			return
 		}
 
+		audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceWOD, resourceID: wodID, after: wodInput.WODInput})
+		if wodInput.ActivityInput != nil {
+			audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceActivity, resourceID: activityID, after: wodInput.ActivityInput})
+		}
+
 		if wodInput.ActivityInput == nil {
 			c.JSON(http.StatusOK, "Added a WOD")
 		} else {
+			activitiesAdded(dataSource, userID)
 			c.JSON(http.StatusOK, "Added a WOD and Activity")
 		}
 	}
@@ -167,14 +257,62 @@ func AddActivity(dataSource *sql.DB) gin.HandlerFunc {
 		} else if activityInput.TimeTaken == 0 {
 			c.JSON(http.StatusBadRequest, "Please provide a time taken")
 			return
+		} else if !validActivityTier(c, activityInput) {
+			return
 		}
 
-		err = db.CreateActivity(dataSource, activityInput, userID)
-		if err != nil {
+		activityID, err := db.CreateActivity(dataSource, activityInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
 			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating activity: %q", err))
 			return
 		}
 
+		audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceActivity, resourceID: activityID, after: activityInput})
+
+		activitiesAdded(dataSource, userID)
+
 		c.JSON(http.StatusCreated, "Added an Activity")
 	}
 }
+
+// validScaling checks a WOD's scaling options, writing an error response if they aren't valid
+func validScaling(c *gin.Context, wod data.WODInput) bool {
+	seen := map[string]bool{}
+	for _, option := range wod.Scaling {
+		if !data.ValidTier(option.Tier) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid scaling tier (Rx, Scaled or Foundations)")
+			return false
+		} else if seen[option.Tier] {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Scaling tier '%s' was given more than once", option.Tier))
+			return false
+		}
+		seen[option.Tier] = true
+	}
+
+	return true
+}
+
+// validActivityTier checks the tier an Activity was done at, writing an error response if it isn't valid
+func validActivityTier(c *gin.Context, activity data.ActivityInput) bool {
+	if activity.Tier != nil && !data.ValidTier(*activity.Tier) {
+		c.JSON(http.StatusBadRequest, "Please provide a valid tier (Rx, Scaled or Foundations)")
+		return false
+	}
+
+	return true
+}
+
+// activitiesAdded evaluates the user's goals and achievements after Activities are added. Failing to doesn't fail
+// the request as both are evaluated again whenever they're read.
+func activitiesAdded(dataSource *sql.DB, userID int) {
+	if err := db.EvaluateGoals(dataSource, userID, time.Now().Unix()); err != nil {
+		fmt.Printf("error evaluating goals: %+v", err)
+	}
+
+	if _, err := db.AwardAchievements(dataSource, data.AchievementRules, userID); err != nil {
+		fmt.Printf("error awarding achievements: %+v", err)
+	}
+}
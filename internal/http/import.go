@@ -0,0 +1,98 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// ImportActivities will add Activities (and any new WODs) from an uploaded CSV file
+func ImportActivities(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Please provide a valid dryRun (true or false)")
+			return
+		}
+
+		var mapping data.ImportMapping
+		format := c.DefaultQuery("format", "csv")
+		if format == "csv" {
+			if err := json.Unmarshal([]byte(c.PostForm("mapping")), &mapping); err != nil {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading column mapping: %q", err))
+				return
+			}
+		} else {
+			preset, ok := data.ImportFormats[format]
+			if !ok {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("Unknown import format: %q", format))
+				return
+			}
+			mapping = preset
+		}
+
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Please provide a file to import")
+			return
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error opening file: %q", err))
+			return
+		}
+		defer file.Close()
+
+		rows, failures, err := data.ParseImport(file, mapping)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading file: %q", err))
+			return
+		}
+
+		// Any row that couldn't be parsed stops the whole import, but the rest are still checked
+		report, err := db.ImportActivities(dataSource, rows, userID, dryRun || len(failures) > 0)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error importing activities: %q", err))
+			return
+		}
+
+		report.DryRun = dryRun
+		report.Rows += len(failures)
+		report.Results = append(report.Results, failures...)
+		sort.Slice(report.Results, func(i, j int) bool {
+			return report.Results[i].Line < report.Results[j].Line
+		})
+
+		if !report.Committed && !dryRun {
+			c.JSON(http.StatusUnprocessableEntity, report)
+			return
+		}
+
+		if report.Committed {
+			audit(c, dataSource, change{
+				action:       data.AuditImport,
+				resourceType: data.ResourceActivities,
+				after:        gin.H{"format": format, "rows": report.Rows, "wodsCreated": report.WODsCreated, "activitiesCreated": report.ActivitiesCreated},
+			})
+			activitiesAdded(dataSource, userID)
+		}
+
+		c.JSON(http.StatusOK, report)
+	}
+}
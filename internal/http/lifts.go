@@ -0,0 +1,230 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddLift will log a lift, reporting whether it's a new PR for the movement
+func AddLift(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		liftInput := data.LiftInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&liftInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with lift details: %q", err))
+			return
+		}
+
+		if data.NormaliseExercise(liftInput.Movement) == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a movement (e.g. back squat)")
+			return
+		} else if liftInput.Date == 0 {
+			c.JSON(http.StatusBadRequest, "Please provide a date")
+			return
+		} else if liftInput.Sets < 1 || liftInput.Reps < 1 {
+			c.JSON(http.StatusBadRequest, "Please provide the sets and reps (at least 1 each)")
+			return
+		} else if liftInput.Load.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, "Please provide a load (e.g. 100kg or 225lb)")
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		if err := liftInput.SI(system); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with load: %q", err))
+			return
+		}
+
+		liftID, err := db.CreateLift(dataSource, liftInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Activity not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating lift: %q", err))
+			return
+		}
+
+		filters := &data.LiftFilter{Movement: liftInput.Movement, Formula: data.FormulaEpley}
+		liftResult, err := db.GetLifts(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading lifts: %q", err))
+			return
+		}
+
+		prs := data.BestLifts(liftResult)
+		pr := len(prs) == 1 && prs[0].LiftID == liftID
+
+		c.JSON(http.StatusCreated, gin.H{"id": liftID, "pr": pr})
+	}
+}
+
+// GetLifts will get and return a user's lift history (can be filtered)
+func GetLifts(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.LiftFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		liftResult, err := db.GetLifts(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading lifts: %q", err))
+			return
+		}
+
+		for i := range liftResult {
+			liftResult[i].In(system)
+		}
+
+		c.JSON(http.StatusOK, liftResult)
+	}
+}
+
+// DeleteLift will delete a lift
+func DeleteLift(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		liftID := c.Param("liftID")
+
+		err = db.DeleteLift(dataSource, liftID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Lift not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting lift: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Deleted lift")
+	}
+}
+
+// GetLiftPRs will get and return a user's best lift for each movement (can be filtered), compared with their
+// bodyweight at the time
+func GetLiftPRs(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.LiftFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		liftResult, err := db.GetLifts(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading lifts: %q", err))
+			return
+		}
+
+		bodyweights, err := db.GetMetrics(dataSource, &data.MetricFilter{Type: data.MetricBodyweight}, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading bodyweight: %q", err))
+			return
+		}
+
+		prs := data.BestLifts(liftResult)
+		data.AddRelativeStrength(prs, bodyweights)
+		for i := range prs {
+			prs[i].In(system)
+		}
+
+		c.JSON(http.StatusOK, prs)
+	}
+}
+
+// GetLiftPercentages will get and return working weights for a movement from the user's estimated one rep max
+func GetLiftPercentages(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.LiftFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		if filters.Movement == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a movement")
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		liftResult, err := db.GetLifts(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading lifts: %q", err))
+			return
+		}
+
+		prs := data.BestLifts(liftResult)
+		if len(prs) == 0 {
+			c.JSON(http.StatusNotFound, "No lifts to estimate a one rep max from")
+			return
+		}
+		prs[0].In(system)
+
+		c.JSON(http.StatusOK, data.LiftPercentages{
+			Movement:    prs[0].Movement,
+			Formula:     filters.Formula,
+			OneRM:       prs[0].EstimatedOneRM,
+			Percentages: data.Percentages(prs[0].EstimatedOneRM),
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package http
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/lockout"
+)
+
+// retryAfterKey is where how long a turned away login has to wait is kept until the response is written
+const retryAfterKey = "retryAfter"
+
+// ErrTooManyLogins is returned when there have been too many failed logins to an account or from a client
+var ErrTooManyLogins = errors.New("too many failed logins, please try again later")
+
+// Authenticate checks the username and password given to log in, starting a session for the User they belong to.
+// Logins are counted as failed per account and client by guard before the password is checked (and given back if
+// it's right), which turns logins away once there are too many.
+func Authenticate(dataSource *sql.DB, guard *lockout.Guard) func(c *gin.Context) (interface{}, error) {
+	return func(c *gin.Context) (interface{}, error) {
+		var loginVals data.Login
+		if err := c.ShouldBind(&loginVals); err != nil {
+			return "", jwt.ErrMissingLoginValues
+		}
+
+		now := time.Now()
+		ip := clientIP(c)
+
+		wait, err := guard.Begin(c, loginVals.Username, ip, now)
+		if err != nil {
+			log.Printf("counting login err: %v", err)
+			return nil, jwt.ErrFailedAuthentication
+		} else if wait > 0 {
+			audit(c, dataSource, change{
+				action:       data.AuditLoginBlocked,
+				resourceType: data.ResourceLogin,
+				after:        gin.H{"method": "password", "username": loginVals.Username, "retryAfter": int(wait / time.Second)},
+			})
+			c.Set(retryAfterKey, wait)
+			return nil, ErrTooManyLogins
+		}
+
+		// Fetch login user
+		user, err := db.GetUser(dataSource, loginVals)
+		if err == sql.ErrNoRows {
+			audit(c, dataSource, change{
+				action:       data.AuditLoginFailed,
+				resourceType: data.ResourceLogin,
+				after:        gin.H{"method": "password", "username": loginVals.Username},
+			})
+			return nil, jwt.ErrFailedAuthentication
+		} else if err != nil {
+			log.Printf("fetching user err: %v", err)
+			return nil, jwt.ErrFailedAuthentication
+		}
+
+		if err := guard.Succeed(c, loginVals.Username, ip); err != nil {
+			log.Printf("clearing failed logins err: %v", err)
+		}
+
+		if err := StartSession(c, dataSource, &user); err != nil {
+			log.Printf("starting session err: %v", err)
+			return nil, jwt.ErrFailedTokenCreation
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditLogin,
+			resourceType: data.ResourceUser,
+			resourceID:   user.ID,
+			after:        gin.H{"method": "password"},
+			actor:        &user,
+		})
+
+		return &user, nil
+	}
+}
+
+// LoginStatus returns the status code for an unauthorized response: 429 (saying when to try again) if the
+// login was turned away for too many failures, otherwise code
+func LoginStatus(c *gin.Context, code int) int {
+	wait := c.GetDuration(retryAfterKey)
+	if wait <= 0 {
+		return code
+	}
+
+	c.Header("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+	return http.StatusTooManyRequests
+}
+
+// GetLoginLockout will get and return the failed logins to an account or from a client IP (for admins)
+func GetLoginLockout(guard *lockout.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scope, subject, ok := lockoutSubject(c)
+		if !ok {
+			return
+		}
+
+		attempts, err := guard.Status(c, scope, subject)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading failed logins: %q", err))
+			return
+		}
+
+		lockoutResult := data.LoginLockout{
+			Scope:    scope,
+			Subject:  subject,
+			Failures: attempts.Failures,
+			Blocked:  attempts.BlockedUntil.After(time.Now()),
+		}
+		if !attempts.LastFailure.IsZero() {
+			lastFailure := attempts.LastFailure.Unix()
+			lockoutResult.LastFailureAt = &lastFailure
+		}
+		if !attempts.BlockedUntil.IsZero() {
+			blockedUntil := attempts.BlockedUntil.Unix()
+			lockoutResult.BlockedUntil = &blockedUntil
+		}
+
+		c.JSON(http.StatusOK, lockoutResult)
+	}
+}
+
+// UnlockLogin will forget the failed logins to an account or from a client IP so logging in can be tried again
+// straight away (for admins)
+func UnlockLogin(guard *lockout.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := GetUser(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		scope, subject, ok := lockoutSubject(c)
+		if !ok {
+			return
+		}
+
+		err = guard.Unlock(c, scope, subject, user.Username, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error unlocking logins: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Unlocked logins")
+	}
+}
+
+// lockoutSubject reads whose failed logins are wanted (an account or client IP) from the path, writing an error
+// response if the scope isn't valid
+func lockoutSubject(c *gin.Context) (string, string, bool) {
+	scope, subject := c.Param("scope"), c.Param("subject")
+
+	if scope != lockout.ScopeAccount && scope != lockout.ScopeIP {
+		c.JSON(http.StatusBadRequest, "Please provide a valid scope (account or ip)")
+		return "", "", false
+	} else if subject == "" {
+		c.JSON(http.StatusBadRequest, "Please provide a username or IP address")
+		return "", "", false
+	}
+
+	return scope, subject, true
+}
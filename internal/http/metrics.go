@@ -0,0 +1,241 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddMetric will record a body metric or benchmark test
+func AddMetric(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metricInput := data.MetricInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&metricInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with metric details: %q", err))
+			return
+		}
+
+		if !validMetric(c, dataSource, &metricInput, userID) {
+			return
+		}
+
+		metricID, err := db.CreateMetric(dataSource, metricInput, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating metric: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": metricID})
+	}
+}
+
+// GetMetrics will get and return a user's metrics (can be filtered)
+func GetMetrics(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.MetricFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		metricResult, err := db.GetMetrics(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading metrics: %q", err))
+			return
+		}
+
+		for i := range metricResult {
+			metricResult[i].In(system)
+		}
+
+		c.JSON(http.StatusOK, metricResult)
+	}
+}
+
+// GetMetric will get and return an individual metric
+func GetMetric(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		metricID := c.Param("metricID")
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		metricResult, err := db.GetMetric(dataSource, metricID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Metric not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading metric: %q", err))
+			return
+		}
+
+		metricResult.In(system)
+
+		c.JSON(http.StatusOK, metricResult)
+	}
+}
+
+// UpdateMetric will change a metric
+func UpdateMetric(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		metricInput := data.MetricInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&metricInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with metric details: %q", err))
+			return
+		}
+
+		if !validMetric(c, dataSource, &metricInput, userID) {
+			return
+		}
+
+		metricID := c.Param("metricID")
+
+		err = db.UpdateMetric(dataSource, metricID, metricInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Metric not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating metric: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Updated metric")
+	}
+}
+
+// DeleteMetric will delete a metric
+func DeleteMetric(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		metricID := c.Param("metricID")
+
+		err = db.DeleteMetric(dataSource, metricID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Metric not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting metric: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Deleted metric")
+	}
+}
+
+// GetMetricTrends will get and return how each of a user's metrics has changed (can be filtered)
+func GetMetricTrends(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.MetricFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		metricResult, err := db.GetMetrics(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading metrics: %q", err))
+			return
+		}
+
+		trends := data.MetricTrends(metricResult)
+		for i := range trends {
+			trends[i].In(system)
+		}
+
+		c.JSON(http.StatusOK, trends)
+	}
+}
+
+// validMetric checks a metric and converts it to the units it's stored in, writing an error response if it can't
+func validMetric(c *gin.Context, dataSource *sql.DB, metric *data.MetricInput, userID int) bool {
+	if !data.ValidMetricType(metric.Type) {
+		c.JSON(http.StatusBadRequest, "Please provide a type (bodyweight, bodyFat, restingHeartRate or benchmark)")
+		return false
+	} else if metric.Type == data.MetricBenchmark && (metric.Name == nil || *metric.Name == "") {
+		c.JSON(http.StatusBadRequest, "Please provide the name of the benchmark")
+		return false
+	} else if metric.Date == 0 {
+		c.JSON(http.StatusBadRequest, "Please provide a date")
+		return false
+	}
+
+	if metric.Type != data.MetricBenchmark {
+		metric.Name = nil
+	}
+
+	system, ok := userUnits(c, dataSource, userID)
+	if !ok {
+		return false
+	}
+
+	if err := metric.SI(system); err != nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with metric value: %q", err))
+		return false
+	}
+
+	return true
+}
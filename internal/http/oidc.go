@@ -0,0 +1,247 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	jwt "github.com/appleboy/gin-jwt/v2"
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/oidc"
+)
+
+// ssoTimeout is how long talking to the single sign-on provider can take before giving up
+const ssoTimeout = 30 * time.Second
+
+// stateCookie holds the hash of the state of the single sign-on started in the browser, and is only sent back
+// to the callback
+const (
+	stateCookie     = "oidc_state"
+	stateCookiePath = "/login/oidc/callback"
+)
+
+// SSO is what's needed to log users in with a single sign-on (OpenID Connect) provider
+type SSO struct {
+	Provider *oidc.Provider
+	// AutoProvision creates an account for someone logging in for the first time who has no account to link to
+	AutoProvision bool
+}
+
+// StartOIDCLogin will send the user to the single sign-on provider to log in (coming back to OIDCCallback)
+func StartOIDCLogin(dataSource *sql.DB, sso SSO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authURL, err := startOIDCLogin(c, dataSource, sso, nil)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error starting single sign-on: %q", err))
+			return
+		}
+
+		c.Redirect(http.StatusFound, authURL)
+	}
+}
+
+// OIDCCallback will finish logging in with the single sign-on provider, returning the same access and refresh
+// tokens as /login (or linking the identity to the user who started linking it). It has to come from the browser
+// that started the single sign-on, so nobody can send someone else a link that logs them in as (or links their
+// identity to) an account they don't own.
+func OIDCCallback(dataSource *sql.DB, sso SSO, mw *jwt.GinJWTMiddleware) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stateHash, _ := c.Cookie(stateCookie)
+		setStateCookie(c, "", -1)
+
+		if providerError := c.Query("error"); providerError != "" {
+			unauthorized(c, mw, http.StatusUnauthorized, fmt.Sprintf("single sign-on failed: %s %s", providerError, c.Query("error_description")))
+			return
+		}
+
+		code, state := c.Query("code"), c.Query("state")
+		if code == "" || state == "" {
+			c.JSON(http.StatusBadRequest, "Please provide the code and state from the single sign-on provider")
+			return
+		} else if subtle.ConstantTimeCompare([]byte(stateHash), []byte(data.HashToken(state))) != 1 {
+			c.JSON(http.StatusBadRequest, "Single sign-on was started in another browser, please try again")
+			return
+		}
+
+		login, err := db.UseOIDCLogin(dataSource, state)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusBadRequest, "Single sign-on has expired or already finished, please try again")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading single sign-on: %q", err))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, ssoTimeout)
+		defer cancel()
+
+		identity, err := sso.Provider.Exchange(ctx, code, login.CodeVerifier, login.Nonce)
+		if err != nil {
+			fmt.Printf("single sign-on exchange err: %v \n", err)
+			unauthorized(c, mw, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(jwt.ErrFailedAuthentication, c))
+			return
+		}
+
+		if login.LinkUserID != nil {
+			err = db.LinkIdentity(dataSource, identity, *login.LinkUserID)
+			if err == db.ErrIdentityLinked {
+				c.JSON(http.StatusConflict, "That identity is already linked to another account")
+				return
+			} else if err != nil {
+				c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error linking identity: %q", err))
+				return
+			}
+
+			c.JSON(http.StatusOK, "Linked identity")
+			return
+		}
+
+		user, err := db.GetIdentityUser(dataSource, identity, sso.AutoProvision)
+		if err == db.ErrNoLinkedUser {
+			unauthorized(c, mw, http.StatusForbidden, "no account is linked to that identity, please log in and link it first")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading user: %q", err))
+			return
+		}
+
+		if err := StartSession(c, dataSource, &user); err != nil {
+			fmt.Printf("starting session err: %v \n", err)
+			unauthorized(c, mw, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(jwt.ErrFailedTokenCreation, c))
+			return
+		}
+
+		token, expire, err := mw.TokenGenerator(&user)
+		if err != nil {
+			unauthorized(c, mw, http.StatusUnauthorized, mw.HTTPStatusMessageFunc(jwt.ErrFailedTokenCreation, c))
+			return
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditLogin,
+			resourceType: data.ResourceUser,
+			resourceID:   user.ID,
+			after:        gin.H{"method": "oidc", "issuer": identity.Issuer},
+			actor:        &user,
+		})
+
+		tokenResponse(c, token, expire, c.GetString(refreshTokenKey))
+	}
+}
+
+// LinkIdentity will return where to send the logged in user to log in with the single sign-on provider, linking
+// the identity they log in as to their account when they come back
+func LinkIdentity(dataSource *sql.DB, sso SSO) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		authURL, err := startOIDCLogin(c, dataSource, sso, &userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error starting single sign-on: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"url": authURL})
+	}
+}
+
+// GetIdentities will get and return the single sign-on identities linked to the logged in user
+func GetIdentities(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		identityResult, err := db.GetIdentities(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading identities: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, identityResult)
+	}
+}
+
+// UnlinkIdentity will stop the logged in user logging in with a single sign-on identity
+func UnlinkIdentity(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		identityID := c.Param("identityID")
+
+		err = db.UnlinkIdentity(dataSource, identityID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Identity not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error unlinking identity: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Unlinked identity")
+	}
+}
+
+// startOIDCLogin keeps a new login with the single sign-on provider (linking to linkUserID if it's given), ties
+// it to the browser with a cookie and returns where to send the user to log in
+func startOIDCLogin(c *gin.Context, dataSource *sql.DB, sso SSO, linkUserID *int) (string, error) {
+	state, _, err := data.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, _, err := data.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	verifier, _, err := data.NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	login := data.OIDCLogin{Nonce: nonce, CodeVerifier: verifier, LinkUserID: linkUserID}
+	if err := db.CreateOIDCLogin(dataSource, state, login, data.OIDCLoginTTL); err != nil {
+		return "", err
+	}
+
+	setStateCookie(c, data.HashToken(state), int(data.OIDCLoginTTL/time.Second))
+
+	ctx, cancel := context.WithTimeout(c, ssoTimeout)
+	defer cancel()
+
+	return sso.Provider.AuthCodeURL(ctx, state, nonce, verifier)
+}
+
+// setStateCookie sets (or with a negative maxAge, clears) the cookie tying single sign-on to the browser. It's Lax
+// rather than Strict so it's sent when the provider redirects back.
+func setStateCookie(c *gin.Context, stateHash string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     stateCookie,
+		Value:    stateHash,
+		Path:     stateCookiePath,
+		MaxAge:   maxAge,
+		Secure:   c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,233 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddPlannedSession will plan a session
+func AddPlannedSession(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionInput := data.PlannedSessionInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&sessionInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with planned session details: %q", err))
+			return
+		}
+
+		if !validPlannedSession(c, sessionInput) {
+			return
+		}
+
+		plannedID, err := db.CreatePlannedSession(dataSource, sessionInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating planned session: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": plannedID})
+	}
+}
+
+// GetPlannedSessions will get and return planned sessions (can be filtered)
+func GetPlannedSessions(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.PlannedSessionFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		sessionResult, err := db.GetPlannedSessions(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading planned sessions: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, sessionResult)
+	}
+}
+
+// GetToday will get and return the sessions planned for today
+func GetToday(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		loc, err := db.GetLocation(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading time zone: %q", err))
+			return
+		}
+
+		today := time.Unix(data.StartOfDay(time.Now().Unix(), loc), 0)
+		filters := &data.PlannedSessionFilter{
+			StartDate: today,
+			EndDate:   today,
+		}
+
+		sessionResult, err := db.GetPlannedSessions(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading planned sessions: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, sessionResult)
+	}
+}
+
+// GetPlannedSession will get and return an individual planned session
+func GetPlannedSession(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		plannedID := c.Param("plannedID")
+
+		sessionResult, err := db.GetPlannedSession(dataSource, plannedID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Planned session not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading planned session: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, sessionResult)
+	}
+}
+
+// UpdatePlannedSession will change a planned session
+func UpdatePlannedSession(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sessionInput := data.PlannedSessionInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&sessionInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with planned session details: %q", err))
+			return
+		}
+
+		if !validPlannedSession(c, sessionInput) {
+			return
+		}
+
+		plannedID := c.Param("plannedID")
+
+		err = db.UpdatePlannedSession(dataSource, plannedID, sessionInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Planned session not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating planned session: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Updated planned session")
+	}
+}
+
+// DeletePlannedSession will delete a planned session
+func DeletePlannedSession(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		plannedID := c.Param("plannedID")
+
+		err = db.DeletePlannedSession(dataSource, plannedID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Planned session not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting planned session: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Deleted planned session")
+	}
+}
+
+// GetAdherence will compare planned sessions with those completed (can be filtered by date)
+func GetAdherence(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.PlannedSessionFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		adherenceResult, err := db.GetAdherence(dataSource, filters, userID, time.Now().Unix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading adherence: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, adherenceResult)
+	}
+}
+
+func validPlannedSession(c *gin.Context, session data.PlannedSessionInput) bool {
+	if session.WODID == nil {
+		c.JSON(http.StatusBadRequest, "Please provide a WOD ID")
+		return false
+	} else if session.PlannedDate == 0 {
+		c.JSON(http.StatusBadRequest, "Please provide a planned date")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,223 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/storage"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// GetMe will get and return the logged in user's profile
+func GetMe(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		profileResult, err := db.GetProfile(dataSource, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "User not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading profile: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, profileResult)
+	}
+}
+
+// UpdateMe will change the parts of the logged in user's profile given and return the profile (emailing a link to
+// verify a new email address)
+func UpdateMe(dataSource *sql.DB, m Mail) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		profileInput := data.ProfileInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&profileInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with profile details: %q", err))
+			return
+		}
+
+		if !validProfile(c, &profileInput) {
+			return
+		}
+
+		emailChanged, err := db.UpdateProfile(dataSource, profileInput, userID)
+		if err == db.ErrEmailTaken {
+			c.JSON(http.StatusConflict, "That email address is already used by another account")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating profile: %q", err))
+			return
+		}
+
+		if emailChanged {
+			if err := sendEmailVerification(c, dataSource, m, userID, *profileInput.Email); err != nil {
+				fmt.Printf("error sending verification email: %+v", err)
+			}
+		}
+
+		profileResult, err := db.GetProfile(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading profile: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, profileResult)
+	}
+}
+
+// ChangePassword will change the logged in user's password once their current password is checked
+func ChangePassword(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		passwordInput := data.PasswordChange{}
+
+		user, err := GetUser(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&passwordInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with password details: %q", err))
+			return
+		}
+
+		if passwordInput.CurrentPassword == "" {
+			c.JSON(http.StatusBadRequest, "Please provide your current password")
+			return
+		} else if utf8.RuneCountInString(passwordInput.NewPassword) < data.MinPasswordLength {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Please provide a new password of at least %d characters", data.MinPasswordLength))
+			return
+		}
+
+		err = db.ChangePassword(dataSource, passwordInput, user.Session, user.ID)
+		if err == db.ErrWrongPassword {
+			c.JSON(http.StatusForbidden, "Current password is wrong")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error changing password: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditPasswordChange,
+			resourceType: data.ResourceUser,
+			resourceID:   user.ID,
+			after:        gin.H{"sessionsRevoked": "others"},
+		})
+
+		c.JSON(http.StatusOK, "Changed password")
+	}
+}
+
+// DeleteMe will delete the logged in user's Activities and WODs (anonymising WODs others use) and their account,
+// along with the pictures of the deleted WODs
+func DeleteMe(dataSource *sql.DB, store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		pictures, err := db.DeleteAccount(dataSource, userID)
+		if err == db.ErrLastGymOwner {
+			c.JSON(http.StatusConflict, "Please make someone else an owner of your gyms first")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting account: %q", err))
+			return
+		}
+
+		// The snapshot is kept small, as the account is being deleted so as not to keep the user's data
+		audit(c, dataSource, change{
+			action:       data.AuditDelete,
+			resourceType: data.ResourceUser,
+			resourceID:   userID,
+			after:        gin.H{"wodsDeleted": len(pictures)},
+		})
+
+		for wodID, wodPicture := range pictures {
+			deletePictures(c, store, strconv.Itoa(wodID), wodPicture.Picture, wodPicture.Thumbnail)
+		}
+
+		c.JSON(http.StatusOK, "Deleted account")
+	}
+}
+
+// validProfile checks (and tidies) the profile changes given, writing an error response if they aren't valid
+func validProfile(c *gin.Context, profile *data.ProfileInput) bool {
+	if profile.Email != nil {
+		email := data.NormaliseEmail(*profile.Email)
+		profile.Email = &email
+
+		if email != "" && !data.ValidEmail(email) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid email address")
+			return false
+		}
+	}
+
+	if profile.DisplayName != nil {
+		displayName := strings.TrimSpace(*profile.DisplayName)
+		profile.DisplayName = &displayName
+
+		if !data.ValidDisplayName(displayName) {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Display names can be at most %d characters", data.MaxDisplayNameLength))
+			return false
+		}
+	}
+
+	if profile.Avatar != nil && *profile.Avatar != "" && !data.ValidAvatar(*profile.Avatar) {
+		c.JSON(http.StatusBadRequest, "Please provide an avatar URL (http or https)")
+		return false
+	}
+
+	if profile.TimeZone != nil && !data.ValidTimeZone(*profile.TimeZone) {
+		c.JSON(http.StatusBadRequest, "Please provide a valid time zone (e.g. Europe/London)")
+		return false
+	}
+
+	if profile.Units != nil && !units.ValidSystem(*profile.Units) {
+		c.JSON(http.StatusBadRequest, "Please provide units (metric or imperial)")
+		return false
+	}
+
+	if profile.BirthYear != nil && !data.ValidBirthYear(*profile.BirthYear, time.Now()) {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("Please provide a birth year between %d and now", data.EarliestBirthYear))
+		return false
+	}
+
+	if profile.Division != nil && *profile.Division != "" && !data.ValidDivision(*profile.Division) {
+		c.JSON(http.StatusBadRequest, "Please provide a valid division (men, women or nonbinary)")
+		return false
+	}
+
+	return true
+}
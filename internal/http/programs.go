@@ -0,0 +1,250 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// AddProgram will create a program of WODs
+func AddProgram(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		programInput := data.ProgramInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&programInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with program details: %q", err))
+			return
+		}
+
+		if programInput.Name == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a name")
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		for w, week := range programInput.Weeks {
+			if week.Week < 1 {
+				c.JSON(http.StatusBadRequest, "Please provide a week number (from 1)")
+				return
+			}
+			for d, day := range week.Days {
+				if day.Day < 1 || day.Day > 7 {
+					c.JSON(http.StatusBadRequest, "Please provide a day number (1 to 7)")
+					return
+				}
+				for i, wod := range day.WODs {
+					if wod.Type == "" {
+						c.JSON(http.StatusBadRequest, "Please provide a Type (e.g. WOD, Girls, Hero)")
+						return
+					}
+					if !validScaling(c, wod) {
+						return
+					}
+					if err := programInput.Weeks[w].Days[d].WODs[i].SI(system); err != nil {
+						c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with scaling: %q", err))
+						return
+					}
+					if wod.GymID != nil && !canPostToGym(c, dataSource, *wod.GymID, userID) {
+						return
+					}
+					if wod.Visibility == "" {
+						// Programming stays with the athletes it's assigned to unless the coach says otherwise
+						programInput.Weeks[w].Days[d].WODs[i].Visibility = data.VisibilityPrivate
+					} else if !data.ValidVisibility(wod.Visibility) {
+						c.JSON(http.StatusBadRequest, "Please provide a valid visibility (private, gym or public)")
+						return
+					}
+				}
+			}
+		}
+
+		programID, wodIDs, err := db.CreateProgram(dataSource, programInput, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating program: %q", err))
+			return
+		}
+
+		// The program's WODs are recorded like those added on their own, in the order they were created
+		i := 0
+		for _, week := range programInput.Weeks {
+			for _, day := range week.Days {
+				for _, wod := range day.WODs {
+					audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceWOD, resourceID: wodIDs[i], after: wod})
+					i++
+				}
+			}
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": programID})
+	}
+}
+
+// GetProgram will get and return an individual program and its workouts
+func GetProgram(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		programID := c.Param("programID")
+
+		programResult, err := db.GetProgram(dataSource, programID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Program not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading program: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, programResult)
+	}
+}
+
+// GetPrograms will get and return the programs a user created or was assigned
+func GetPrograms(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		programResult, err := db.GetPrograms(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading programs: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, programResult)
+	}
+}
+
+// AssignProgram will assign a program to athletes and groups from a start date
+func AssignProgram(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		assignmentInput := data.ProgramAssignmentInput{}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&assignmentInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with assignment details: %q", err))
+			return
+		}
+
+		if len(assignmentInput.UserIDs) == 0 && len(assignmentInput.GroupIDs) == 0 {
+			c.JSON(http.StatusBadRequest, "Please provide athletes or groups to assign the program to")
+			return
+		} else if assignmentInput.StartDate == 0 {
+			c.JSON(http.StatusBadRequest, "Please provide a start date")
+			return
+		}
+
+		programID := c.Param("programID")
+
+		err = db.AssignProgram(dataSource, programID, assignmentInput, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Program not found")
+			return
+		} else if err == db.ErrForbidden {
+			c.JSON(http.StatusForbidden, "You can only assign programs to athletes and groups you coach")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error assigning program: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Assigned program")
+	}
+}
+
+// GetProgramProgress will get and return how far athletes have got through a program
+func GetProgramProgress(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		programID := c.Param("programID")
+
+		progressResult, err := db.GetProgramProgress(dataSource, programID, userID, time.Now().Unix())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading program progress: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, progressResult)
+	}
+}
+
+// GetProgramming will get and return the program workouts assigned to a user for a day (defaults to today)
+func GetProgramming(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		day := time.Now()
+		if date := c.Query("date"); date != "" {
+			day, err = data.ParseDateTimeString(date)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, fmt.Sprintf("Invalid value for date format parameter 'date': '%s'", date))
+				return
+			}
+		}
+
+		loc, err := db.GetLocation(dataSource, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading time zone: %q", err))
+			return
+		}
+
+		// Workouts are scheduled from midnight in the coach's time zone, so the whole of the athlete's day is used
+		start := data.StartOfDay(day.Unix(), loc)
+		end := data.NextDay(start, loc) - 1
+
+		workoutResult, err := db.GetAssignedWorkouts(dataSource, userID, start, end)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading programming: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, workoutResult)
+	}
+}
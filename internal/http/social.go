@@ -0,0 +1,258 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/notify"
+)
+
+// GetFeed will get and return a page of recent Activities by the user, people they follow and people they share a gym with
+func GetFeed(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		filters, err := data.PageFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		feedResult, err := db.GetFeed(dataSource, filters, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading feed: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, feedResult)
+	}
+}
+
+// AddComment will add the user's comment to an Activity or WOD and let its owner know
+func AddComment(dataSource *sql.DB, notifier notify.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		commentInput := data.CommentInput{}
+
+		user, err := GetUser(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		target, ok := commentTarget(c)
+		if !ok {
+			return
+		}
+
+		err = c.Bind(&commentInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with comment details: %q", err))
+			return
+		}
+
+		commentInput.Body = strings.TrimSpace(commentInput.Body)
+		if commentInput.Body == "" {
+			c.JSON(http.StatusBadRequest, "Please provide a comment")
+			return
+		} else if len([]rune(commentInput.Body)) > data.MaxCommentLength {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Comments can be at most %d characters", data.MaxCommentLength))
+			return
+		}
+
+		commentID, ownerID, err := db.CreateComment(dataSource, target, commentInput, user.ID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, targetNotFound(target))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating comment: %q", err))
+			return
+		}
+
+		if ownerID != 0 && ownerID != user.ID {
+			notification := notify.Notification{
+				Type:       notify.TypeComment,
+				UserID:     ownerID,
+				ActorID:    user.ID,
+				ActorName:  user.Username,
+				ActivityID: target.ActivityID,
+				WODID:      target.WODID,
+				CommentID:  commentID,
+				Message:    fmt.Sprintf("%s commented: %s", user.Username, commentInput.Body),
+			}
+
+			if err := notifier.Notify(c, notification); err != nil {
+				fmt.Printf("error sending comment notification: %+v", err)
+			}
+		}
+
+		c.JSON(http.StatusCreated, gin.H{"id": commentID})
+	}
+}
+
+// GetComments will get and return a page of the comments on an Activity or WOD with its reactions
+func GetComments(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		target, ok := commentTarget(c)
+		if !ok {
+			return
+		}
+
+		filters, err := data.PageFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading filters: %q", err))
+			return
+		}
+
+		commentResult, err := db.GetComments(dataSource, target, filters, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, targetNotFound(target))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading comments: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, commentResult)
+	}
+}
+
+// DeleteComment will delete a comment the user wrote or that was left on their Activity or WOD
+func DeleteComment(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		commentID := c.Param("commentID")
+
+		err = db.DeleteComment(dataSource, commentID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Comment not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error deleting comment: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Deleted comment")
+	}
+}
+
+// AddReaction will leave the user's reaction on an Activity or WOD
+func AddReaction(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		target, ok := commentTarget(c)
+		if !ok {
+			return
+		}
+
+		kind := c.Param("kind")
+		if !data.ValidReaction(kind) {
+			c.JSON(http.StatusBadRequest, "Please provide a valid reaction (like, fire, strong or clap)")
+			return
+		}
+
+		err = db.SetReaction(dataSource, target, kind, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, targetNotFound(target))
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error adding reaction: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Added reaction")
+	}
+}
+
+// RemoveReaction will take back the user's reaction on an Activity or WOD
+func RemoveReaction(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		target, ok := commentTarget(c)
+		if !ok {
+			return
+		}
+
+		err = db.RemoveReaction(dataSource, target, c.Param("kind"), userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Reaction not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error removing reaction: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Removed reaction")
+	}
+}
+
+// commentTarget reads the Activity or WOD being commented on or reacted to from the path, writing an error
+// response if its ID isn't valid
+func commentTarget(c *gin.Context) (data.CommentTarget, bool) {
+	var target data.CommentTarget
+
+	if param := c.Param("activityID"); param != "" {
+		activityID, err := strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, "Please provide a valid activity ID")
+			return target, false
+		}
+		target.ActivityID = &activityID
+		return target, true
+	}
+
+	wodID, err := strconv.Atoi(c.Param("wodID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "Please provide a valid WOD ID")
+		return target, false
+	}
+	target.WODID = &wodID
+
+	return target, true
+}
+
+// targetNotFound is the error response for an Activity or WOD that doesn't exist or the user can't see
+func targetNotFound(target data.CommentTarget) string {
+	if target.ActivityID != nil {
+		return "Activity not found"
+	}
+	return "WOD not found"
+}
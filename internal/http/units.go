@@ -0,0 +1,78 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/units"
+)
+
+// GetUnits will get and return the unit system the user sees loads and distances in
+func GetUnits(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		system, ok := userUnits(c, dataSource, userID)
+		if !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"units": system})
+	}
+}
+
+// UpdateUnits will change the unit system the user sees loads and distances in
+func UpdateUnits(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var unitsInput struct {
+			Units units.System `json:"units"`
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&unitsInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with units details: %q", err))
+			return
+		}
+
+		if !units.ValidSystem(unitsInput.Units) {
+			c.JSON(http.StatusBadRequest, "Please provide units (metric or imperial)")
+			return
+		}
+
+		err = db.UpdateUnits(dataSource, unitsInput.Units, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating units: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, "Updated units")
+	}
+}
+
+// userUnits gets the unit system the user has picked, writing an error response if it can't
+func userUnits(c *gin.Context, dataSource *sql.DB, userID int) (units.System, bool) {
+	system, err := db.GetUnits(dataSource, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading units: %q", err))
+		return "", false
+	}
+
+	return system, true
+}
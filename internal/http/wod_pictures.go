@@ -0,0 +1,161 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+	"github.com/philLITERALLY/wodland-service/internal/picture"
+	"github.com/philLITERALLY/wodland-service/internal/storage"
+)
+
+// UploadWODPicture will store an uploaded picture (and a thumbnail of it) and set it as a WOD's picture, deleting
+// the one it replaces
+func UploadWODPicture(dataSource *sql.DB, store storage.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		wodID := c.Param("wodID")
+
+		previous, err := db.GetWODPicture(dataSource, wodID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading wod: %q", err))
+			return
+		}
+
+		upload, contentType, ok := readPicture(c)
+		if !ok {
+			return
+		}
+
+		thumbnail, err := picture.Thumbnail(upload)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error reading picture: %q", err))
+			return
+		}
+
+		// A random name stops old copies being served from caches when a picture is replaced, and stops pictures of
+		// WODs that aren't public being found by guessing their URL
+		name, _, err := data.NewToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error storing picture: %q", err))
+			return
+		}
+		key := pictureKeyPrefix(wodID) + name
+
+		pictureURL, err := store.Put(c, key+"."+picture.Extensions[contentType], contentType, bytes.NewReader(upload), int64(len(upload)))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error storing picture: %q", err))
+			return
+		}
+
+		thumbnailURL, err := store.Put(c, key+"_thumb.jpg", "image/jpeg", bytes.NewReader(thumbnail), int64(len(thumbnail)))
+		if err != nil {
+			deletePictures(c, store, wodID, &pictureURL)
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error storing thumbnail: %q", err))
+			return
+		}
+
+		err = db.UpdateWODPicture(dataSource, wodID, pictureURL, thumbnailURL, userID)
+		if err != nil {
+			deletePictures(c, store, wodID, &pictureURL, &thumbnailURL)
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating wod: %q", err))
+			return
+		}
+
+		deletePictures(c, store, wodID, previous.Picture, previous.Thumbnail)
+
+		audit(c, dataSource, change{
+			action:       data.AuditUpdate,
+			resourceType: data.ResourceWOD,
+			resourceID:   wodID,
+			before:       previous,
+			after:        data.WODPicture{Picture: &pictureURL, Thumbnail: &thumbnailURL},
+		})
+
+		c.JSON(http.StatusOK, gin.H{
+			"picture":   pictureURL,
+			"thumbnail": thumbnailURL,
+		})
+	}
+}
+
+// deletePictures deletes the pictures of a WOD at urls that were uploaded to store for it (leaving any linked from
+// elsewhere, or uploaded for another WOD). Failing to delete one is only logged, as all it does is leave the
+// picture behind.
+func deletePictures(ctx context.Context, store storage.Store, wodID string, urls ...*string) {
+	for _, pictureURL := range urls {
+		if pictureURL == nil {
+			continue
+		}
+
+		if key, ok := store.Key(*pictureURL); ok && strings.HasPrefix(key, pictureKeyPrefix(wodID)) {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Printf("deleting picture %s err: %v", key, err)
+			}
+		}
+	}
+}
+
+// pictureKeyPrefix is what the keys of a WOD's pictures start with
+func pictureKeyPrefix(wodID string) string {
+	return fmt.Sprintf("wods/%s/", wodID)
+}
+
+// readPicture reads and validates the "picture" file of a multipart upload, writing an error response if
+// it can't be used
+func readPicture(c *gin.Context) ([]byte, string, bool) {
+	fileHeader, err := c.FormFile("picture")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, "Please provide a picture")
+		return nil, "", false
+	}
+
+	if fileHeader.Size > picture.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, fmt.Sprintf("Picture must be smaller than %dMB", picture.MaxSize>>20))
+		return nil, "", false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error opening picture: %q", err))
+		return nil, "", false
+	}
+	defer file.Close()
+
+	upload, err := ioutil.ReadAll(io.LimitReader(file, picture.MaxSize+1))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading picture: %q", err))
+		return nil, "", false
+	}
+
+	contentType, err := picture.Validate(upload)
+	if errors.Is(err, picture.ErrTooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, "Picture is too large")
+		return nil, "", false
+	} else if err != nil {
+		c.JSON(http.StatusUnsupportedMediaType, err.Error())
+		return nil, "", false
+	}
+
+	return upload, contentType, true
+}
@@ -0,0 +1,41 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/ocr"
+)
+
+// ocrTimeout is how long reading a picture can take before giving up
+const ocrTimeout = 30 * time.Second
+
+// PreviewWOD will read the text from an uploaded picture of a whiteboard and return it as a draft WOD
+// description to be reviewed before the WOD is saved
+func PreviewWOD(engine ocr.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		upload, _, ok := readPicture(c)
+		if !ok {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c, ocrTimeout)
+		defer cancel()
+
+		text, err := engine.Extract(ctx, upload)
+		if err != nil {
+			fmt.Printf("error reading picture text: %+v", err)
+			c.JSON(http.StatusServiceUnavailable, "Unable to read text from picture")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"text":     text,
+			"exercise": ocr.Draft(text),
+		})
+	}
+}
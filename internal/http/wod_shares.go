@@ -0,0 +1,173 @@
+package http
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	_ "github.com/heroku/x/hmetrics/onload"
+	"github.com/philLITERALLY/wodland-service/internal/data"
+	"github.com/philLITERALLY/wodland-service/internal/data/db"
+)
+
+// ShareWOD will create a link that gives anyone who opens it read access to a WOD
+func ShareWOD(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		token, tokenHash, err := data.NewToken()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating share link: %q", err))
+			return
+		}
+
+		wodID := c.Param("wodID")
+
+		err = db.CreateWODShare(dataSource, wodID, tokenHash, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error creating share link: %q", err))
+			return
+		}
+
+		// The token is left out so the audit log can't be used to open the WOD
+		audit(c, dataSource, change{action: data.AuditCreate, resourceType: data.ResourceWODShare, resourceID: wodID})
+
+		c.JSON(http.StatusCreated, gin.H{
+			"token": token,
+			"url":   "/SharedWOD/" + token,
+		})
+	}
+}
+
+// RevokeWODShares will stop a WOD's share links from working
+func RevokeWODShares(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		wodID := c.Param("wodID")
+
+		err = db.RevokeWODShares(dataSource, wodID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error revoking share links: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{action: data.AuditDelete, resourceType: data.ResourceWODShare, resourceID: wodID})
+
+		c.JSON(http.StatusOK, "Revoked share links")
+	}
+}
+
+// GetSharedWOD will give the user access to the WOD a share link is for and return it
+func GetSharedWOD(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		token := c.Param("token")
+
+		wodID, err := db.RedeemWODShare(dataSource, data.HashToken(token), userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "Share link not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading share link: %q", err))
+			return
+		}
+
+		wodResult, err := db.GetWOD(dataSource, wodID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading wod: %q", err))
+			return
+		}
+
+		c.JSON(http.StatusOK, wodResult)
+	}
+}
+
+// UpdateWODVisibility will change who can see a WOD
+func UpdateWODVisibility(dataSource *sql.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var visibilityInput struct {
+			Visibility string `json:"visibility"`
+		}
+
+		userID, err := GetUserID(c)
+		if err != nil {
+			fmt.Printf("%+v", err)
+			c.JSON(http.StatusBadRequest, err)
+			return
+		}
+
+		err = c.Bind(&visibilityInput)
+		if err != nil {
+			fmt.Printf("error binding request input: %+v", err)
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("Error with visibility details: %q", err))
+			return
+		}
+
+		if !data.ValidVisibility(visibilityInput.Visibility) {
+			c.JSON(http.StatusBadRequest, "Please provide a visibility (private, gym or public)")
+			return
+		}
+
+		wodID := c.Param("wodID")
+
+		wod, err := db.GetWOD(dataSource, wodID, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error reading wod: %q", err))
+			return
+		}
+
+		if visibilityInput.Visibility == data.VisibilityGym && wod.GymID == nil {
+			c.JSON(http.StatusBadRequest, "Only a gym's WODs can be visible to the gym")
+			return
+		}
+
+		err = db.UpdateWODVisibility(dataSource, wodID, visibilityInput.Visibility, userID)
+		if err == sql.ErrNoRows {
+			c.JSON(http.StatusNotFound, "WOD not found")
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, fmt.Sprintf("Error updating wod: %q", err))
+			return
+		}
+
+		audit(c, dataSource, change{
+			action:       data.AuditUpdate,
+			resourceType: data.ResourceWOD,
+			resourceID:   wodID,
+			before:       gin.H{"visibility": wod.Visibility},
+			after:        gin.H{"visibility": visibilityInput.Visibility},
+		})
+
+		c.JSON(http.StatusOK, "Updated WOD visibility")
+	}
+}
@@ -0,0 +1,143 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxLineOctets is the longest a content line may be before it must be folded (RFC 5545 section 3.1)
+const maxLineOctets = 75
+
+const dateTimeFormat = "20060102T150405Z"
+
+const dateFormat = "20060102"
+
+// Event is a single VEVENT in a calendar. All day events are on Start's date in its location, so Start should be
+// in the time zone the day is in.
+type Event struct {
+	UID         string
+	Start       time.Time
+	AllDay      bool
+	Duration    time.Duration
+	Summary     string
+	Description string
+}
+
+// Encoder writes a VCALENDAR one Event at a time so large calendars don't have to be held in memory
+type Encoder struct {
+	w     *bufio.Writer
+	stamp time.Time
+	err   error
+}
+
+// NewEncoder starts a calendar called name on w
+func NewEncoder(w io.Writer, name string) *Encoder {
+	e := &Encoder{w: bufio.NewWriter(w), stamp: time.Now().UTC()}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", "-//wodland//wodland-service//EN")
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if name != "" {
+		e.line("X-WR-CALNAME", escapeText(name))
+	}
+
+	return e
+}
+
+// Encode writes a single Event
+func (e *Encoder) Encode(event Event) error {
+	e.line("BEGIN", "VEVENT")
+	e.line("UID", escapeText(event.UID))
+	e.line("DTSTAMP", e.stamp.Format(dateTimeFormat))
+	if event.AllDay {
+		e.line("DTSTART;VALUE=DATE", event.Start.Format(dateFormat))
+	} else {
+		e.line("DTSTART", event.Start.UTC().Format(dateTimeFormat))
+	}
+	if event.Duration > 0 {
+		e.line("DURATION", formatDuration(event.Duration))
+	}
+	e.line("SUMMARY", escapeText(event.Summary))
+	if event.Description != "" {
+		e.line("DESCRIPTION", escapeText(event.Description))
+	}
+	e.line("END", "VEVENT")
+
+	return e.err
+}
+
+// Close ends the calendar and flushes anything still buffered
+func (e *Encoder) Close() error {
+	e.line("END", "VCALENDAR")
+	if e.err != nil {
+		return e.err
+	}
+
+	return e.w.Flush()
+}
+
+// line writes a folded content line terminated by CRLF
+func (e *Encoder) line(name string, value string) {
+	if e.err != nil {
+		return
+	}
+
+	content := name + ":" + value
+	limit := maxLineOctets
+	for len(content) > limit {
+		cut := limit
+		// Don't split a multi-byte UTF-8 character across lines
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+
+		if _, e.err = e.w.WriteString(content[:cut] + "\r\n "); e.err != nil {
+			return
+		}
+
+		// The leading space of a continuation line counts towards its length
+		content = content[cut:]
+		limit = maxLineOctets - 1
+	}
+
+	_, e.err = e.w.WriteString(content + "\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	"\\", "\\\\",
+	";", "\\;",
+	",", "\\,",
+	"\r\n", "\\n",
+	"\n", "\\n",
+)
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11)
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
+
+// formatDuration formats d as an RFC 5545 DURATION value
+func formatDuration(d time.Duration) string {
+	seconds := int64(d / time.Second)
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	seconds = seconds % 60
+
+	duration := "PT"
+	if hours > 0 {
+		duration += fmt.Sprintf("%dH", hours)
+	}
+	if minutes > 0 {
+		duration += fmt.Sprintf("%dM", minutes)
+	}
+	if seconds > 0 || duration == "PT" {
+		duration += fmt.Sprintf("%dS", seconds)
+	}
+
+	return duration
+}
@@ -0,0 +1,229 @@
+// Package lockout slows down and then stops password guessing by counting failed logins per account and per
+// client IP
+package lockout
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+)
+
+// Scopes failed logins are counted in
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+)
+
+// Types of Event
+const (
+	EventLockout = "login_lockout"
+	EventUnlock  = "login_unlock"
+)
+
+// Attempts are the failed logins recorded for an account or client
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+	// BlockedUntil is when logging in can be tried again (zero if it never stopped)
+	BlockedUntil time.Time
+}
+
+// Store keeps failed logins by key. Every instance of the service sharing a Store shares its counts.
+//
+// A login is counted as failed before its password is checked (and given back if it turns out to be right), so
+// guesses made at the same time can't all get past the check before any of them are counted.
+type Store interface {
+	// Get returns the failed logins recorded for key
+	Get(ctx context.Context, key string) (Attempts, error)
+	// Reserve counts a login for key at now unless key is blocked, blocking it for as long as policy says for the
+	// new count (starting the count again if the last one was more than policy.ResetAfter before). It returns the
+	// failed logins recorded and whether the login was counted, all in one step.
+	Reserve(ctx context.Context, key string, now time.Time, policy Policy) (Attempts, bool, error)
+	// Release gives back a login counted by Reserve that succeeded, unblocking key if the count is back under
+	// what policy lets through
+	Release(ctx context.Context, key string, policy Policy) error
+	// Reset forgets the failed logins for key
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy is how failed logins are slowed down and locked out
+type Policy struct {
+	// FreeFailures can be made before having to wait between tries
+	FreeFailures int
+	// BaseDelay is the wait after the first failure past FreeFailures, doubling after each one after that
+	BaseDelay time.Duration
+	// MaxDelay is the longest wait between tries before being locked out
+	MaxDelay time.Duration
+	// LockoutFailures locks logins out for LockoutDuration (and again after each failure once it's over)
+	LockoutFailures int
+	LockoutDuration time.Duration
+	// ResetAfter is how long after the last failure the count starts again
+	ResetAfter time.Duration
+}
+
+// DefaultAccountPolicy is the Policy for failed logins to an account
+var DefaultAccountPolicy = Policy{
+	FreeFailures:    3,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutFailures: 10,
+	LockoutDuration: 30 * time.Minute,
+	ResetAfter:      24 * time.Hour,
+}
+
+// DefaultIPPolicy is the Policy for failed logins from a client IP, which is more lenient as many people can
+// share an address (e.g. at a gym)
+var DefaultIPPolicy = Policy{
+	FreeFailures:    20,
+	BaseDelay:       time.Second,
+	MaxDelay:        5 * time.Minute,
+	LockoutFailures: 100,
+	LockoutDuration: time.Hour,
+	ResetAfter:      time.Hour,
+}
+
+// delay returns how long to wait after failures, and whether that's a lockout
+func (p Policy) delay(failures int) (time.Duration, bool) {
+	if p.LockoutFailures > 0 && failures >= p.LockoutFailures {
+		return p.LockoutDuration, true
+	} else if failures <= p.FreeFailures {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := p.FreeFailures + 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay, false
+}
+
+// Event is a security event about logins being locked out or unlocked
+type Event struct {
+	Type    string    `json:"type"`
+	Scope   string    `json:"scope"`
+	Subject string    `json:"subject"`
+	Time    time.Time `json:"time"`
+	// Failures and Until are set for lockouts, as is the client IP the last failure came from
+	Failures int        `json:"failures,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	IP       string     `json:"ip,omitempty"`
+	// Actor is who unlocked the account or client
+	Actor string `json:"actor,omitempty"`
+}
+
+// LogEvent writes event to the log as JSON
+func LogEvent(ctx context.Context, event Event) {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("security event: %+v", event)
+		return
+	}
+
+	log.Printf("security event: %s", encoded)
+}
+
+// Guard decides whether a login can be tried, counting failed logins per account and per client IP
+type Guard struct {
+	Store   Store
+	Account Policy
+	IP      Policy
+	// OnEvent is told about lockouts and unlocks
+	OnEvent func(ctx context.Context, event Event)
+}
+
+// NewGuard returns a Guard keeping counts in store with the default policies, logging its events
+func NewGuard(store Store) *Guard {
+	return &Guard{Store: store, Account: DefaultAccountPolicy, IP: DefaultIPPolicy, OnEvent: LogEvent}
+}
+
+// Begin counts a login to username from ip as failed before its password is checked (whether or not the account
+// exists), returning how long until they can try again instead if either is blocked. Call Succeed if the password
+// turns out to be right.
+func (g *Guard) Begin(ctx context.Context, username string, ip string, now time.Time) (time.Duration, error) {
+	accountWait, err := g.reserve(ctx, ScopeAccount, username, g.Account, ip, now)
+	if err != nil || accountWait > 0 {
+		return accountWait, err
+	}
+
+	ipWait, err := g.reserve(ctx, ScopeIP, ip, g.IP, ip, now)
+	if err != nil || ipWait > 0 {
+		// The login isn't being tried, so it shouldn't count against the account
+		if releaseErr := g.Store.Release(ctx, key(ScopeAccount, username), g.Account); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+		return ipWait, err
+	}
+
+	return 0, nil
+}
+
+// Succeed forgets the failed logins to username and gives back the login Begin counted from ip (the other failed
+// logins from ip are kept, so one account an attacker knows the password of can't be used to carry on guessing
+// others)
+func (g *Guard) Succeed(ctx context.Context, username string, ip string) error {
+	if err := g.Store.Reset(ctx, key(ScopeAccount, username)); err != nil {
+		return err
+	}
+
+	return g.Store.Release(ctx, key(ScopeIP, ip), g.IP)
+}
+
+// Status returns the failed logins recorded in scope for subject (a username or client IP)
+func (g *Guard) Status(ctx context.Context, scope string, subject string) (Attempts, error) {
+	return g.Store.Get(ctx, key(scope, subject))
+}
+
+// Unlock forgets the failed logins in scope for subject (a username or client IP) so logging in can be tried
+// again straight away. actor is who unlocked it.
+func (g *Guard) Unlock(ctx context.Context, scope string, subject string, actor string, now time.Time) error {
+	if err := g.Store.Reset(ctx, key(scope, subject)); err != nil {
+		return err
+	}
+
+	g.event(ctx, Event{Type: EventUnlock, Scope: scope, Subject: normalise(scope, subject), Time: now, Actor: actor})
+	return nil
+}
+
+// reserve counts a login for subject in scope, returning how long until it can be tried again if subject is
+// blocked (telling OnEvent when the count locks it out)
+func (g *Guard) reserve(ctx context.Context, scope string, subject string, policy Policy, ip string, now time.Time) (time.Duration, error) {
+	attempts, reserved, err := g.Store.Reserve(ctx, key(scope, subject), now, policy)
+	if err != nil {
+		return 0, err
+	} else if !reserved {
+		return attempts.BlockedUntil.Sub(now), nil
+	}
+
+	if _, locked := policy.delay(attempts.Failures); locked {
+		until := attempts.BlockedUntil
+		g.event(ctx, Event{Type: EventLockout, Scope: scope, Subject: normalise(scope, subject), Time: now, Failures: attempts.Failures, Until: &until, IP: ip})
+	}
+
+	return 0, nil
+}
+
+// event tells OnEvent about event
+func (g *Guard) event(ctx context.Context, event Event) {
+	if g.OnEvent != nil {
+		g.OnEvent(ctx, event)
+	}
+}
+
+// key is what failed logins for subject in scope are stored under
+func key(scope string, subject string) string {
+	return scope + ":" + normalise(scope, subject)
+}
+
+// normalise makes guesses at "Athlete" and "athlete " count against the same account
+func normalise(scope string, subject string) string {
+	if scope == ScopeAccount {
+		return strings.ToLower(strings.TrimSpace(subject))
+	}
+	return subject
+}
@@ -0,0 +1,104 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often keys that no longer matter are forgotten
+const sweepInterval = time.Hour
+
+// Memory is a Store keeping failed logins in memory, so each instance of the service counts separately and
+// counts are lost on restart
+type Memory struct {
+	mu        sync.Mutex
+	attempts  map[string]*memoryAttempts
+	lastSweep time.Time
+}
+
+type memoryAttempts struct {
+	Attempts
+	resetAfter time.Duration
+}
+
+// NewMemory returns an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{attempts: map[string]*memoryAttempts{}}
+}
+
+// Get returns the failed logins recorded for key
+func (m *Memory) Get(ctx context.Context, key string) (Attempts, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if attempts, ok := m.attempts[key]; ok {
+		return attempts.Attempts, nil
+	}
+	return Attempts{}, nil
+}
+
+// Reserve counts a login for key at now unless it's blocked
+func (m *Memory) Reserve(ctx context.Context, key string, now time.Time, policy Policy) (Attempts, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if now.Sub(m.lastSweep) > sweepInterval {
+		m.sweep(now)
+	}
+
+	attempts, ok := m.attempts[key]
+	if ok && attempts.BlockedUntil.After(now) {
+		return attempts.Attempts, false, nil
+	} else if !ok || now.Sub(attempts.LastFailure) > policy.ResetAfter {
+		attempts = &memoryAttempts{}
+		m.attempts[key] = attempts
+	}
+
+	attempts.Failures++
+	attempts.LastFailure = now
+	attempts.BlockedUntil = time.Time{}
+	attempts.resetAfter = policy.ResetAfter
+	if delay, _ := policy.delay(attempts.Failures); delay > 0 {
+		attempts.BlockedUntil = now.Add(delay)
+	}
+
+	return attempts.Attempts, true, nil
+}
+
+// Release gives back a login counted by Reserve
+func (m *Memory) Release(ctx context.Context, key string, policy Policy) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	attempts, ok := m.attempts[key]
+	if !ok || attempts.Failures == 0 {
+		return nil
+	}
+
+	attempts.Failures--
+	if delay, _ := policy.delay(attempts.Failures); delay == 0 {
+		attempts.BlockedUntil = time.Time{}
+	}
+	return nil
+}
+
+// Reset forgets the failed logins for key
+func (m *Memory) Reset(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.attempts, key)
+	return nil
+}
+
+// sweep forgets keys whose count would start again and that aren't blocked
+func (m *Memory) sweep(now time.Time) {
+	for key, attempts := range m.attempts {
+		if now.Sub(attempts.LastFailure) > attempts.resetAfter && now.After(attempts.BlockedUntil) {
+			delete(m.attempts, key)
+		}
+	}
+
+	m.lastSweep = now
+}
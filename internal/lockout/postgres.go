@@ -0,0 +1,196 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Postgres is a Store keeping failed logins in the login_attempt table, so every instance of the service shares
+// the counts. Times are stored as unix seconds.
+type Postgres struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgres returns a Postgres store using db
+func NewPostgres(db *sql.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// Get returns the failed logins recorded for key
+func (p *Postgres) Get(ctx context.Context, key string) (Attempts, error) {
+	selectQuery := psql.
+		Select("failures, last_failure_at, blocked_until").
+		From("login_attempt").
+		Where(sq.Eq{"key": key})
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	attempts, err := scanAttempts(p.db.QueryRowContext(ctx, sqlQuery, args...))
+	if err == sql.ErrNoRows {
+		return Attempts{}, nil
+	}
+	return attempts, err
+}
+
+// Reserve counts a login for key at now unless it's blocked. The row is locked while it's read and updated, so
+// logins to the same key made at the same time are counted one after another. Blocks are rounded up to the second.
+func (p *Postgres) Reserve(ctx context.Context, key string, now time.Time, policy Policy) (Attempts, bool, error) {
+	if err := p.sweep(ctx, now); err != nil {
+		return Attempts{}, false, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Attempts{}, false, err
+	}
+	defer tx.Rollback()
+
+	attempts, err := lockAttempts(ctx, tx, key, now, policy.ResetAfter)
+	if err != nil {
+		return attempts, false, err
+	} else if attempts.BlockedUntil.After(now) {
+		return attempts, false, nil
+	}
+
+	if attempts.LastFailure.Before(now.Add(-policy.ResetAfter)) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	attempts.BlockedUntil = time.Time{}
+
+	var blockedUntil int64
+	if delay, _ := policy.delay(attempts.Failures); delay > 0 {
+		blockedUntil = roundUp(now.Add(delay))
+		attempts.BlockedUntil = time.Unix(blockedUntil, 0)
+	}
+
+	updateQuery := psql.
+		Update("login_attempt").
+		Set("failures", attempts.Failures).
+		Set("last_failure_at", now.Unix()).
+		Set("blocked_until", blockedUntil).
+		Set("forget_at", sq.Expr("GREATEST(forget_at, ?, ?)", now.Add(policy.ResetAfter).Unix(), blockedUntil)).
+		Where(sq.Eq{"key": key})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	if _, err := tx.ExecContext(ctx, sqlQuery, args...); err != nil {
+		return attempts, false, err
+	}
+
+	return attempts, true, tx.Commit()
+}
+
+// Release gives back a login counted by Reserve
+func (p *Postgres) Release(ctx context.Context, key string, policy Policy) error {
+	// Failures above the number policy lets through keep their block
+	blockedUntil := sq.Expr("CASE WHEN failures - 1 <= ? AND failures - 1 < ? THEN 0 ELSE blocked_until END",
+		policy.FreeFailures, lockoutFailures(policy))
+
+	updateQuery := psql.
+		Update("login_attempt").
+		Set("blocked_until", blockedUntil).
+		Set("failures", sq.Expr("GREATEST(failures - 1, 0)")).
+		Where(sq.Eq{"key": key})
+	sqlQuery, args, _ := updateQuery.ToSql()
+
+	_, err := p.db.ExecContext(ctx, sqlQuery, args...)
+	return err
+}
+
+// Reset forgets the failed logins for key
+func (p *Postgres) Reset(ctx context.Context, key string) error {
+	deleteQuery := psql.
+		Delete("login_attempt").
+		Where(sq.Eq{"key": key})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	_, err := p.db.ExecContext(ctx, sqlQuery, args...)
+	return err
+}
+
+// sweep deletes counts that have started again and aren't blocked, at most once every sweepInterval
+func (p *Postgres) sweep(ctx context.Context, now time.Time) error {
+	p.mu.Lock()
+	if now.Sub(p.lastSweep) < sweepInterval {
+		p.mu.Unlock()
+		return nil
+	}
+	p.lastSweep = now
+	p.mu.Unlock()
+
+	deleteQuery := psql.
+		Delete("login_attempt").
+		Where(sq.Lt{"forget_at": now.Unix()}).
+		Where(sq.Lt{"blocked_until": now.Unix()})
+	sqlQuery, args, _ := deleteQuery.ToSql()
+
+	_, err := p.db.ExecContext(ctx, sqlQuery, args...)
+	return err
+}
+
+// lockAttempts locks the row for key (adding it if there isn't one yet) and returns its failed logins
+func lockAttempts(ctx context.Context, tx *sql.Tx, key string, now time.Time, resetAfter time.Duration) (Attempts, error) {
+	insertQuery := psql.
+		Insert("login_attempt").
+		Columns("key, failures, last_failure_at, blocked_until, forget_at").
+		Values(key, 0, now.Unix(), 0, now.Add(resetAfter).Unix()).
+		Suffix("ON CONFLICT (key) DO NOTHING")
+	sqlInsertQuery, insertArgs, _ := insertQuery.ToSql()
+
+	if _, err := tx.ExecContext(ctx, sqlInsertQuery, insertArgs...); err != nil {
+		return Attempts{}, err
+	}
+
+	selectQuery := psql.
+		Select("failures, last_failure_at, blocked_until").
+		From("login_attempt").
+		Where(sq.Eq{"key": key}).
+		Suffix("FOR UPDATE")
+	sqlQuery, args, _ := selectQuery.ToSql()
+
+	return scanAttempts(tx.QueryRowContext(ctx, sqlQuery, args...))
+}
+
+// lockoutFailures is the count policy locks out at (one past any count if it never does)
+func lockoutFailures(policy Policy) int {
+	if policy.LockoutFailures > 0 {
+		return policy.LockoutFailures
+	}
+	return math.MaxInt32
+}
+
+// roundUp returns t in unix seconds, rounded up
+func roundUp(t time.Time) int64 {
+	seconds := t.Unix()
+	if t.After(time.Unix(seconds, 0)) {
+		seconds++
+	}
+	return seconds
+}
+
+// scanAttempts reads failures, last_failure_at and blocked_until
+func scanAttempts(row *sql.Row) (Attempts, error) {
+	var attempts Attempts
+	var lastFailure, blockedUntil int64
+
+	if err := row.Scan(&attempts.Failures, &lastFailure, &blockedUntil); err != nil {
+		return attempts, err
+	}
+
+	attempts.LastFailure = time.Unix(lastFailure, 0)
+	if blockedUntil > 0 {
+		attempts.BlockedUntil = time.Unix(blockedUntil, 0)
+	}
+
+	return attempts, nil
+}
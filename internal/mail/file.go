@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileDrop is a Mailer that writes each email to a .eml file in a directory instead of sending it, for
+// development and tests
+type FileDrop struct {
+	Dir  string
+	From string
+}
+
+// NewFileDrop returns a FileDrop writing to dir, creating it if needed
+func NewFileDrop(dir string, from string) (*FileDrop, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &FileDrop{Dir: dir, From: from}, nil
+}
+
+// Send writes message to a new file
+func (f *FileDrop) Send(ctx context.Context, message Message) error {
+	if !validHeader(message.To) || !validHeader(message.Subject) {
+		return errors.New("invalid email header")
+	}
+
+	now := time.Now()
+
+	file, err := ioutil.TempFile(f.Dir, fmt.Sprintf("%d-*.eml", now.UnixNano()))
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(format(f.From, message, now)); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	return file.Close()
+}
+
+// Files lists the emails written so far, oldest first
+func (f *FileDrop) Files() ([]string, error) {
+	return filepath.Glob(filepath.Join(f.Dir, "*.eml"))
+}
@@ -0,0 +1,76 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Message is an email to send
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// PasswordReset is the email sent with a link to reset a password
+func PasswordReset(to string, link string, expires time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Reset your wodland password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your wodland account.\n\n"+
+			"Reset it here (the link works once and expires in %s):\n\n%s\n\n"+
+			"If it wasn't you, you can ignore this email and your password won't change.\n", duration(expires), link),
+	}
+}
+
+// EmailVerification is the email sent with a link to verify an email address
+func EmailVerification(to string, link string, expires time.Duration) Message {
+	return Message{
+		To:      to,
+		Subject: "Verify your wodland email address",
+		Body: fmt.Sprintf("Please verify this email address for your wodland account.\n\n"+
+			"Verify it here (the link works once and expires in %s):\n\n%s\n", duration(expires), link),
+	}
+}
+
+// duration describes d in whole hours or minutes (e.g. "1 hour", "30 minutes")
+func duration(d time.Duration) string {
+	amount, unit := int(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		amount, unit = int(d/time.Hour), "hour"
+	}
+
+	if amount == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", amount, unit)
+}
+
+// format writes message as an RFC 5322 email from from
+func format(from string, message Message, now time.Time) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", message.Subject)
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return buf.Bytes()
+}
+
+// validHeader checks a header value can't add headers of its own
+func validHeader(value string) bool {
+	return !strings.ContainsAny(value, "\r\n")
+}
@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP is a Mailer that sends through an SMTP server (e.g. a local MailHog or Mailpit in development)
+type SMTP struct {
+	// Addr is the server's host:port
+	Addr string
+	// From is the address emails are sent from
+	From string
+	// Username and Password authenticate with the server (no authentication if Username is empty)
+	Username string
+	Password string
+}
+
+// NewSMTP returns a Mailer sending through the SMTP server at addr
+func NewSMTP(addr string, from string, username string, password string) *SMTP {
+	return &SMTP{Addr: addr, From: from, Username: username, Password: password}
+}
+
+// Send sends message
+func (s *SMTP) Send(ctx context.Context, message Message) error {
+	if !validHeader(message.To) || !validHeader(message.Subject) {
+		return errors.New("invalid email header")
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		host, _, err := net.SplitHostPort(s.Addr)
+		if err != nil {
+			return err
+		}
+		auth = smtp.PlainAuth("", s.Username, s.Password, host)
+	}
+
+	// net/smtp doesn't take a context, so send in the background and give up waiting if it's cancelled
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.Addr, auth, s.From, []string{message.To}, format(s.From, message, time.Now()))
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,37 @@
+package notify
+
+import (
+	"context"
+	"log"
+)
+
+// Types of notification
+const (
+	TypeComment = "comment"
+)
+
+// Notification tells a user someone has interacted with something of theirs
+type Notification struct {
+	Type       string `json:"type"`
+	UserID     int    `json:"userID"`
+	ActorID    int    `json:"actorID"`
+	ActorName  string `json:"actorName"`
+	ActivityID *int64 `json:"activityID,omitempty"`
+	WODID      *int   `json:"wodID,omitempty"`
+	CommentID  int64  `json:"commentID,omitempty"`
+	Message    string `json:"message"`
+}
+
+// Notifier delivers notifications (by push, email or however else)
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Log is a Notifier that only logs notifications, for when there's nowhere to deliver them
+type Log struct{}
+
+// Notify logs the notification
+func (Log) Notify(ctx context.Context, notification Notification) error {
+	log.Printf("notify user %d: %s", notification.UserID, notification.Message)
+	return nil
+}
@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// Engine extracts text from an image
+type Engine interface {
+	Extract(ctx context.Context, image []byte) (string, error)
+}
+
+// Draft tidies text read from a whiteboard into a WOD description: whitespace is collapsed and lines
+// that are mostly stray marks rather than words or numbers are dropped
+func Draft(text string) string {
+	var lines []string
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" || !mostlyText(line) {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// mostlyText reports whether at least half of the characters in line are letters or digits
+func mostlyText(line string) bool {
+	var text, other int
+
+	for _, r := range line {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			text++
+		case unicode.IsSpace(r):
+		default:
+			other++
+		}
+	}
+
+	return text >= 2 && text >= other
+}
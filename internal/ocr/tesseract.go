@@ -0,0 +1,46 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Tesseract is an Engine that runs the tesseract command line tool
+type Tesseract struct {
+	// Path to the tesseract binary (looked up on $PATH if empty)
+	Path string
+	// Language is the tesseract language to read (defaults to eng)
+	Language string
+}
+
+// NewTesseract returns a Tesseract engine using the binary at path
+func NewTesseract(path string, language string) *Tesseract {
+	if path == "" {
+		path = "tesseract"
+	}
+	if language == "" {
+		language = "eng"
+	}
+
+	return &Tesseract{Path: path, Language: language}
+}
+
+// Extract reads the text in image
+func (t *Tesseract) Extract(ctx context.Context, image []byte) (string, error) {
+	// Page segmentation mode 6 treats the image as a single block of text, which suits a whiteboard
+	cmd := exec.CommandContext(ctx, t.Path, "stdin", "stdout", "-l", t.Language, "--psm", "6")
+	cmd.Stdin = bytes.NewReader(image)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
@@ -0,0 +1,126 @@
+package picture
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+
+	// Register the formats image.Decode accepts
+	_ "image/gif"
+	_ "image/png"
+)
+
+// MaxSize is the largest picture (in bytes) that can be uploaded
+const MaxSize = 10 << 20
+
+// maxPixels stops small files that decode to enormous images from exhausting memory
+const maxPixels = 50000000
+
+// ThumbnailSize is the longest side (in pixels) of a thumbnail
+const ThumbnailSize = 320
+
+// Extensions are the file extensions for each content type that can be uploaded
+var Extensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// ErrTooLarge is returned for pictures over MaxSize bytes or maxPixels pixels
+var ErrTooLarge = errors.New("picture is too large")
+
+// ErrUnsupported is returned for files that aren't a supported image type
+var ErrUnsupported = errors.New("picture must be a JPEG, PNG or GIF")
+
+// Validate checks picture is a supported image that isn't too large, returning its content type
+func Validate(picture []byte) (string, error) {
+	if len(picture) > MaxSize {
+		return "", ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(picture)
+	if _, ok := Extensions[contentType]; !ok {
+		return "", ErrUnsupported
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(picture))
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	if config.Width*config.Height > maxPixels {
+		return "", ErrTooLarge
+	}
+
+	return contentType, nil
+}
+
+// Thumbnail decodes picture and returns a JPEG no larger than ThumbnailSize on its longest side
+func Thumbnail(picture []byte) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(picture))
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resize(src, ThumbnailSize), &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// resize scales src down (never up) to fit within size × size, averaging the source pixels that make up
+// each destination pixel
+func resize(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	if width <= size && height <= size {
+		return src
+	}
+
+	dstWidth, dstHeight := size, height*size/width
+	if height > width {
+		dstWidth, dstHeight = width*size/height, size
+	}
+	if dstWidth < 1 {
+		dstWidth = 1
+	}
+	if dstHeight < 1 {
+		dstHeight = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := bounds.Min.Y + (y+1)*height/dstHeight
+
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := bounds.Min.X + (x+1)*width/dstWidth
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa)
+					count++
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows each key at most Limit events in any Window. Counts are kept in memory, so each instance
+// of the service limits separately.
+type Limiter struct {
+	Limit  int
+	Window time.Duration
+
+	mu        sync.Mutex
+	events    map[string][]time.Time
+	lastSweep time.Time
+}
+
+// New returns a Limiter allowing limit events per key in each window
+func New(limit int, window time.Duration) *Limiter {
+	return &Limiter{Limit: limit, Window: window, events: map[string][]time.Time{}}
+}
+
+// Allow records an event for key at now, returning false (without recording it) if key has used up its limit
+func (l *Limiter) Allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) > l.Window {
+		l.sweep(now)
+	}
+
+	recent := l.recent(key, now)
+	if len(recent) >= l.Limit {
+		l.events[key] = recent
+		return false
+	}
+
+	l.events[key] = append(recent, now)
+	return true
+}
+
+// recent returns key's events within the window before now
+func (l *Limiter) recent(key string, now time.Time) []time.Time {
+	events := l.events[key]
+
+	start := 0
+	for start < len(events) && now.Sub(events[start]) >= l.Window {
+		start++
+	}
+
+	return events[start:]
+}
+
+// sweep forgets keys with no events in the window before now
+func (l *Limiter) sweep(now time.Time) {
+	for key := range l.events {
+		if len(l.recent(key, now)) == 0 {
+			delete(l.events, key)
+		}
+	}
+
+	l.lastSweep = now
+}
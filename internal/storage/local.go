@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local is a Store that saves blobs to a directory on the local filesystem. The directory is expected to
+// be served at BaseURL.
+type Local struct {
+	Dir     string
+	BaseURL string
+}
+
+// NewLocal returns a Local store saving to dir, creating it if needed
+func NewLocal(dir string, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Local{Dir: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put saves body to a file named by key
+func (l *Local) Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) (string, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so a partial upload is never served
+	file, err := ioutil.TempFile(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, io.LimitReader(body, size)); err != nil {
+		file.Close()
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+
+	return l.BaseURL + "/" + key, nil
+}
+
+// Delete removes the file named by key
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+// Key returns the key of the file at url
+func (l *Local) Key(url string) (string, bool) {
+	key := strings.TrimPrefix(url, l.BaseURL+"/")
+	if key == url || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// path turns key into a path inside Dir
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" {
+		return "", errors.New("invalid key")
+	}
+
+	return filepath.Join(l.Dir, filepath.FromSlash(clean)), nil
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// Store saves blobs and returns the URL they can be fetched from. Anyone with the URL can fetch a blob (so it can
+// be used in an img tag), and stores don't list what they hold, so blobs that shouldn't be public (e.g. pictures
+// of private WODs) have to be saved under keys with a random part nobody can guess.
+type Store interface {
+	// Put saves size bytes of body under key and returns its URL
+	Put(ctx context.Context, key string, contentType string, body io.Reader, size int64) (string, error)
+	// Delete removes the blob saved under key
+	Delete(ctx context.Context, key string) error
+	// Key returns the key of the blob at a URL returned by Put (false if the URL isn't one of the store's)
+	Key(url string) (string, bool)
+}
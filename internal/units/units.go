@@ -0,0 +1,208 @@
+// Package units converts loads, distances and calories between the SI values that are stored and the units
+// athletes enter and read them in.
+package units
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// System is a user's preferred units for display
+type System string
+
+// Unit systems a user can pick
+const (
+	Metric   System = "metric"
+	Imperial System = "imperial"
+)
+
+// ValidSystem checks system is one of the known unit systems
+func ValidSystem(system System) bool {
+	return system == Metric || system == Imperial
+}
+
+// Kind is what a unit measures
+type Kind int
+
+// Kinds of measure
+const (
+	Mass Kind = iota
+	Distance
+	Energy
+)
+
+// Units a Measure can be in (the first of each kind is the SI unit values are stored in)
+const (
+	Kilograms  = "kg"
+	Pounds     = "lb"
+	Metres     = "m"
+	Kilometres = "km"
+	Miles      = "mi"
+	Calories   = "cal"
+)
+
+type unit struct {
+	kind Kind
+	si   float64
+}
+
+var knownUnits = map[string]unit{
+	Kilograms:  {Mass, 1},
+	Pounds:     {Mass, 0.45359237},
+	Metres:     {Distance, 1},
+	Kilometres: {Distance, 1000},
+	Miles:      {Distance, 1609.344},
+	Calories:   {Energy, 1},
+}
+
+var unitAliases = map[string]string{
+	"kgs":  Kilograms,
+	"lbs":  Pounds,
+	"#":    Pounds,
+	"cals": Calories,
+	"kcal": Calories,
+}
+
+var siUnits = map[Kind]string{
+	Mass:     Kilograms,
+	Distance: Metres,
+	Energy:   Calories,
+}
+
+var displayUnits = map[System]map[Kind]string{
+	Metric:   {Mass: Kilograms, Distance: Metres, Energy: Calories},
+	Imperial: {Mass: Pounds, Distance: Miles, Energy: Calories},
+}
+
+// Measure is an amount in a unit, e.g. 95lb. Without a unit the amount is in the user's preferred unit.
+type Measure struct {
+	Amount float64 `json:"amount"`
+	Unit   string  `json:"unit,omitempty"`
+}
+
+// Parse reads a measure like "95lb", "43 kg", "5km" or "20cal" (or a bare number)
+func Parse(value string) (Measure, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	split := strings.IndexFunc(value, func(r rune) bool {
+		return !(r >= '0' && r <= '9' || r == '.' || r == '-' || r == '+')
+	})
+	if split == -1 {
+		split = len(value)
+	}
+
+	amount, err := strconv.ParseFloat(value[:split], 64)
+	if err != nil {
+		return Measure{}, fmt.Errorf("invalid measure '%s'", value)
+	}
+
+	name := strings.TrimSpace(value[split:])
+	if alias, ok := unitAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := knownUnits[name]; name != "" && !ok {
+		return Measure{}, fmt.Errorf("unknown unit '%s'", name)
+	}
+
+	return Measure{Amount: amount, Unit: name}, nil
+}
+
+// UnmarshalJSON accepts a number, a string like "95lb" or an object with an amount and unit
+func (m *Measure) UnmarshalJSON(b []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case float64:
+		*m = Measure{Amount: v}
+	case string:
+		parsed, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case map[string]interface{}:
+		var raw struct {
+			Amount float64 `json:"amount"`
+			Unit   string  `json:"unit"`
+		}
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return err
+		}
+		parsed, err := Parse(strconv.FormatFloat(raw.Amount, 'f', -1, 64) + raw.Unit)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	default:
+		return fmt.Errorf("invalid measure %s", string(b))
+	}
+
+	return nil
+}
+
+// Kind returns what the measure's unit measures (false if it has no unit)
+func (m Measure) Kind() (Kind, bool) {
+	u, ok := knownUnits[m.Unit]
+	return u.kind, ok
+}
+
+// SI converts the measure to the SI unit it's stored in, taking a measure without a unit to be in the system's
+// unit for kind. It's an error for the measure to be a different kind.
+func (m Measure) SI(kind Kind, system System) (Measure, error) {
+	name := m.Unit
+	if name == "" {
+		name = DisplayUnit(kind, system)
+	}
+
+	u, ok := knownUnits[name]
+	if !ok || u.kind != kind {
+		return Measure{}, fmt.Errorf("'%s' isn't a unit of %s", name, kind)
+	}
+
+	return Measure{Amount: m.Amount * u.si, Unit: siUnits[kind]}, nil
+}
+
+// In converts the measure to the system's unit for its kind, rounded to 2 decimal places
+func (m Measure) In(system System) Measure {
+	u, ok := knownUnits[m.Unit]
+	if !ok {
+		return m
+	}
+
+	name := DisplayUnit(u.kind, system)
+	amount := m.Amount * u.si / knownUnits[name].si
+
+	return Measure{Amount: math.Round(amount*100) / 100, Unit: name}
+}
+
+// String formats the measure like "95lb"
+func (m Measure) String() string {
+	return strconv.FormatFloat(m.Amount, 'f', -1, 64) + m.Unit
+}
+
+// DisplayUnit returns the unit a system shows a kind of measure in
+func DisplayUnit(kind Kind, system System) string {
+	if units, ok := displayUnits[system]; ok {
+		return units[kind]
+	}
+	return displayUnits[Metric][kind]
+}
+
+// String names the kind of measure
+func (k Kind) String() string {
+	switch k {
+	case Mass:
+		return "mass"
+	case Distance:
+		return "distance"
+	case Energy:
+		return "energy"
+	}
+	return "unknown"
+}
@@ -0,0 +1,192 @@
+package wearable
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// FIT message and field numbers read from files (everything else is skipped)
+const (
+	fitRecordMessage  = 20
+	fitTimestampField = 253
+	fitHeartRateField = 3
+)
+
+// fitEpoch is when FIT timestamps count from
+var fitEpoch = time.Date(1989, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+var errInvalidFIT = errors.New("invalid FIT file")
+
+type fitField struct {
+	number byte
+	size   int
+}
+
+type fitDefinition struct {
+	order         binary.ByteOrder
+	globalMessage uint16
+	fields        []fitField
+	devDataSize   int
+}
+
+// parseFIT reads the heart rate of the record messages in a Garmin Flexible and Interoperable Data Transfer file
+func parseFIT(r io.Reader) ([]Sample, error) {
+	reader := bufio.NewReader(r)
+
+	headerSize, err := reader.ReadByte()
+	if err != nil || headerSize < 12 {
+		return nil, errInvalidFIT
+	}
+
+	header := make([]byte, headerSize-1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, errInvalidFIT
+	}
+	if string(header[7:11]) != ".FIT" {
+		return nil, errInvalidFIT
+	}
+	dataSize := int64(binary.LittleEndian.Uint32(header[3:7]))
+
+	records := &countingReader{r: io.LimitReader(reader, dataSize)}
+	definitions := map[byte]*fitDefinition{}
+	var samples []Sample
+	var lastTimestamp uint32
+
+	for records.n < dataSize {
+		recordHeader, err := records.readByte()
+		if err != nil {
+			return nil, errInvalidFIT
+		}
+
+		var localMessage byte
+		var compressedTimestamp *uint32
+
+		switch {
+		case recordHeader&0x80 != 0:
+			// Compressed timestamp header: a data message whose timestamp is an offset from the last one
+			localMessage = (recordHeader >> 5) & 0x03
+			offset := uint32(recordHeader & 0x1f)
+			timestamp := lastTimestamp&^0x1f | offset
+			if offset < lastTimestamp&0x1f {
+				timestamp += 0x20
+			}
+			lastTimestamp = timestamp
+			compressedTimestamp = &timestamp
+		case recordHeader&0x40 != 0:
+			definition, err := readFITDefinition(records, recordHeader&0x20 != 0)
+			if err != nil {
+				return nil, err
+			}
+			definitions[recordHeader&0x0f] = definition
+			continue
+		default:
+			localMessage = recordHeader & 0x0f
+		}
+
+		definition, ok := definitions[localMessage]
+		if !ok {
+			return nil, fmt.Errorf("%v: data for undefined message %d", errInvalidFIT, localMessage)
+		}
+
+		sample := Sample{}
+		hasTimestamp := false
+
+		for _, field := range definition.fields {
+			value := make([]byte, field.size)
+			if _, err := io.ReadFull(records, value); err != nil {
+				return nil, errInvalidFIT
+			}
+
+			switch {
+			case field.number == fitTimestampField && field.size == 4:
+				timestamp := definition.order.Uint32(value)
+				if timestamp != 0xffffffff {
+					lastTimestamp = timestamp
+					hasTimestamp = true
+				}
+			case field.number == fitHeartRateField && field.size == 1 && definition.globalMessage == fitRecordMessage:
+				if value[0] != 0xff {
+					sample.HeartRate = int(value[0])
+				}
+			}
+		}
+
+		if _, err := io.CopyN(ioutil.Discard, records, int64(definition.devDataSize)); err != nil {
+			return nil, errInvalidFIT
+		}
+
+		if definition.globalMessage != fitRecordMessage || sample.HeartRate == 0 {
+			continue
+		}
+
+		if compressedTimestamp != nil || hasTimestamp {
+			sample.Time = fitEpoch.Add(time.Duration(lastTimestamp) * time.Second)
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples, nil
+}
+
+func readFITDefinition(records *countingReader, developerData bool) (*fitDefinition, error) {
+	fixed := make([]byte, 5)
+	if _, err := io.ReadFull(records, fixed); err != nil {
+		return nil, errInvalidFIT
+	}
+
+	definition := &fitDefinition{order: binary.LittleEndian}
+	if fixed[1] == 1 {
+		definition.order = binary.BigEndian
+	}
+	definition.globalMessage = definition.order.Uint16(fixed[2:4])
+
+	fields := make([]byte, int(fixed[4])*3)
+	if _, err := io.ReadFull(records, fields); err != nil {
+		return nil, errInvalidFIT
+	}
+	for i := 0; i < len(fields); i += 3 {
+		definition.fields = append(definition.fields, fitField{number: fields[i], size: int(fields[i+1])})
+	}
+
+	if developerData {
+		count, err := records.readByte()
+		if err != nil {
+			return nil, errInvalidFIT
+		}
+
+		devFields := make([]byte, int(count)*3)
+		if _, err := io.ReadFull(records, devFields); err != nil {
+			return nil, errInvalidFIT
+		}
+		for i := 0; i < len(devFields); i += 3 {
+			definition.devDataSize += int(devFields[i+1])
+		}
+	}
+
+	return definition, nil
+}
+
+// countingReader keeps track of how much of the FIT data records have been read
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) readByte() (byte, error) {
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(c, b); err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
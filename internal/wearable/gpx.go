@@ -0,0 +1,47 @@
+package wearable
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// gpxTrackpoint reads heart rate from the Garmin TrackPointExtension, or an hr element directly in the extensions
+// as some other devices write it
+type gpxTrackpoint struct {
+	Time           string `xml:"time"`
+	HeartRate      int    `xml:"extensions>TrackPointExtension>hr"`
+	ExtensionHeart int    `xml:"extensions>hr"`
+}
+
+// parseGPX reads the track points of a GPS Exchange file
+func parseGPX(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+
+	err := decodeElements(r, "trkpt", func(decoder *xml.Decoder, start xml.StartElement) error {
+		var point gpxTrackpoint
+		if err := decoder.DecodeElement(&point, &start); err != nil {
+			return err
+		}
+
+		if point.Time == "" {
+			return nil
+		}
+
+		t, err := time.Parse(time.RFC3339, point.Time)
+		if err != nil {
+			return fmt.Errorf("invalid track point time '%s'", point.Time)
+		}
+
+		heartRate := point.HeartRate
+		if heartRate == 0 {
+			heartRate = point.ExtensionHeart
+		}
+
+		samples = append(samples, Sample{Time: t, HeartRate: heartRate})
+		return nil
+	})
+
+	return samples, err
+}
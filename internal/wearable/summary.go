@@ -0,0 +1,138 @@
+package wearable
+
+import (
+	"math"
+	"time"
+)
+
+// Zones are the heart rate zones as fractions of max heart rate (zone 1 starts at 50%, zone 5 at 90%)
+var Zones = []float64{0.5, 0.6, 0.7, 0.8, 0.9}
+
+// mepsPerMinute are the MEPs earned for each minute in a zone
+var mepsPerMinute = []float64{1, 2, 3, 4, 4}
+
+// maxGap is the longest time (in seconds) a sample counts for, so pauses in recording aren't counted as effort
+const maxGap = 30
+
+// SeriesInterval is the time (in seconds) between the points of a summarised series
+const SeriesInterval = 5
+
+// Summary is the effort worked out from heart rate samples
+type Summary struct {
+	Start            int64   `json:"start"`
+	Duration         int64   `json:"duration"`
+	MaxHeartRate     int     `json:"maxHeartRate"`
+	AverageHeartRate int     `json:"averageHeartRate"`
+	PeakHeartRate    int     `json:"peakHeartRate"`
+	MEPs             int64   `json:"meps"`
+	TRIMP            int64   `json:"trimp"`
+	TimeInZone       []int64 `json:"timeInZone"`
+	SeriesInterval   int64   `json:"seriesInterval"`
+	Series           []int64 `json:"series"`
+}
+
+// Summarise works out the time in each zone, MEPs and TRIMP (Edwards' zone weighted minutes) from samples (oldest
+// first) for someone with maxHeartRate, along with the heart rate every SeriesInterval seconds
+func Summarise(samples []Sample, maxHeartRate int) Summary {
+	summary := Summary{
+		MaxHeartRate:   maxHeartRate,
+		TimeInZone:     make([]int64, len(Zones)),
+		SeriesInterval: SeriesInterval,
+		Series:         []int64{},
+	}
+	if len(samples) == 0 {
+		return summary
+	}
+
+	start := samples[0].Time.Unix()
+	summary.Start = start
+	summary.Duration = samples[len(samples)-1].Time.Unix() - start
+	if maxDuration := int64(MaxDuration / time.Second); summary.Duration > maxDuration {
+		summary.Duration = maxDuration
+	}
+
+	var meps, trimp, weightedHeartRate float64
+	var counted int64
+
+	for i, sample := range samples {
+		if sample.HeartRate > summary.PeakHeartRate {
+			summary.PeakHeartRate = sample.HeartRate
+		}
+
+		if i == len(samples)-1 {
+			break
+		}
+
+		seconds := samples[i+1].Time.Unix() - sample.Time.Unix()
+		if seconds > maxGap {
+			seconds = maxGap
+		}
+		counted += seconds
+		weightedHeartRate += float64(sample.HeartRate * int(seconds))
+
+		if zone := zoneOf(sample.HeartRate, maxHeartRate); zone >= 0 {
+			summary.TimeInZone[zone] += seconds
+			meps += mepsPerMinute[zone] * float64(seconds) / 60
+			trimp += float64(zone+1) * float64(seconds) / 60
+		}
+	}
+
+	if counted > 0 {
+		summary.AverageHeartRate = int(math.Round(weightedHeartRate / float64(counted)))
+	} else {
+		summary.AverageHeartRate = samples[0].HeartRate
+	}
+	summary.MEPs = int64(math.Round(meps))
+	summary.TRIMP = int64(math.Round(trimp))
+	summary.Series = series(samples, start, summary.Duration)
+
+	return summary
+}
+
+// zoneOf returns the index of the zone heartRate is in, or -1 if it's below them all
+func zoneOf(heartRate int, maxHeartRate int) int {
+	if maxHeartRate <= 0 {
+		return -1
+	}
+
+	fraction := float64(heartRate) / float64(maxHeartRate)
+	for zone := len(Zones) - 1; zone >= 0; zone-- {
+		if fraction >= Zones[zone] {
+			return zone
+		}
+	}
+
+	return -1
+}
+
+// series averages samples into SeriesInterval buckets, carrying the last value through buckets without samples.
+// Samples more than duration (which can't be more than MaxDuration) after start are left out.
+func series(samples []Sample, start int64, duration int64) []int64 {
+	if maxDuration := int64(MaxDuration / time.Second); duration > maxDuration {
+		duration = maxDuration
+	}
+
+	buckets := duration/SeriesInterval + 1
+	sums := make([]int64, buckets)
+	counts := make([]int64, buckets)
+
+	for _, sample := range samples {
+		bucket := (sample.Time.Unix() - start) / SeriesInterval
+		if bucket < 0 || bucket >= buckets {
+			continue
+		}
+		sums[bucket] += int64(sample.HeartRate)
+		counts[bucket]++
+	}
+
+	points := make([]int64, buckets)
+	var last int64
+	for i := range points {
+		if counts[i] > 0 {
+			last = int64(math.Round(float64(sums[i]) / float64(counts[i])))
+		}
+		points[i] = last
+	}
+
+	return points
+}
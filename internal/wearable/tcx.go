@@ -0,0 +1,55 @@
+package wearable
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+type tcxTrackpoint struct {
+	Time      string `xml:"Time"`
+	HeartRate int    `xml:"HeartRateBpm>Value"`
+}
+
+// parseTCX reads the trackpoints of a Garmin Training Center file
+func parseTCX(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+
+	err := decodeElements(r, "Trackpoint", func(decoder *xml.Decoder, start xml.StartElement) error {
+		var point tcxTrackpoint
+		if err := decoder.DecodeElement(&point, &start); err != nil {
+			return err
+		}
+
+		t, err := time.Parse(time.RFC3339, point.Time)
+		if err != nil {
+			return fmt.Errorf("invalid trackpoint time '%s'", point.Time)
+		}
+
+		samples = append(samples, Sample{Time: t, HeartRate: point.HeartRate})
+		return nil
+	})
+
+	return samples, err
+}
+
+// decodeElements calls decode for each element named name in an XML document
+func decodeElements(r io.Reader, name string, decode func(*xml.Decoder, xml.StartElement) error) error {
+	decoder := xml.NewDecoder(r)
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == name {
+			if err := decode(decoder, start); err != nil {
+				return err
+			}
+		}
+	}
+}
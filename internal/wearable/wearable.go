@@ -0,0 +1,76 @@
+package wearable
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxSize is the largest file (in bytes) that can be uploaded
+const MaxSize = 20 << 20
+
+// MaxDuration is the longest an activity can last. Samples further than half of it from the middle of a file are
+// left out, so a stray timestamp (e.g. from a watch whose clock wasn't set) can't stretch an activity over years.
+const MaxDuration = 24 * time.Hour
+
+// ErrUnsupported is returned for files that aren't a supported format
+var ErrUnsupported = errors.New("file must be FIT, TCX or GPX")
+
+// ErrNoHeartRate is returned for files without any heart rate samples
+var ErrNoHeartRate = errors.New("file has no heart rate samples")
+
+// Sample is a heart rate reading
+type Sample struct {
+	Time      time.Time
+	HeartRate int
+}
+
+var parsers = map[string]func(io.Reader) ([]Sample, error){
+	".fit": parseFIT,
+	".tcx": parseTCX,
+	".gpx": parseGPX,
+}
+
+// Parse reads the heart rate samples from a FIT, TCX or GPX file (picked by the extension of name), oldest first
+func Parse(name string, r io.Reader) ([]Sample, error) {
+	parse, ok := parsers[strings.ToLower(filepath.Ext(name))]
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	samples, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := samples[:0]
+	for _, sample := range samples {
+		if sample.HeartRate > 0 && !sample.Time.IsZero() {
+			valid = append(valid, sample)
+		}
+	}
+
+	if len(valid) == 0 {
+		return nil, ErrNoHeartRate
+	}
+
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].Time.Before(valid[j].Time)
+	})
+
+	return trim(valid), nil
+}
+
+// trim leaves out the samples (oldest first) further than half of MaxDuration from the median one
+func trim(samples []Sample) []Sample {
+	median := samples[len(samples)/2].Time
+	from, to := median.Add(-MaxDuration/2), median.Add(MaxDuration/2)
+
+	first := sort.Search(len(samples), func(i int) bool { return !samples[i].Time.Before(from) })
+	last := sort.Search(len(samples), func(i int) bool { return samples[i].Time.After(to) })
+
+	return samples[first:last]
+}